@@ -1,6 +1,7 @@
 package teamcity
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 	"time"
@@ -8,21 +9,94 @@ import (
 
 const (
 	dateFormat = "20060102T150405-0700"
+
+	// Build states
+	StateQueued   = "queued"
+	StateRunning  = "running"
+	StateFinished = "finished"
+
+	// Build statuses
+	StatusSuccess = "SUCCESS"
+	StatusFailure = "FAILURE"
+	StatusUnknown = "UNKNOWN"
 )
 
 // Builds is a list of builds
 type Builds struct {
 	Count    int     `json:"count,omitempty"`
+	Href     string  `json:"href,omitempty"`
 	NextHref string  `json:"nextHref,omitempty"`
 	Builds   []Build `json:"build"`
 }
 
+// FilterByStatus returns the builds with the given status, e.g. StatusSuccess
+func (bs *Builds) FilterByStatus(status string) *Builds {
+	return bs.filter(func(b *Build) bool { return b.Status == status })
+}
+
+// FilterByState returns the builds in the given state, e.g. StateFinished
+func (bs *Builds) FilterByState(state string) *Builds {
+	return bs.filter(func(b *Build) bool { return b.State == state })
+}
+
+// GroupByBuildType partitions the builds by their BuildTypeId
+func (bs *Builds) GroupByBuildType() map[string]*Builds {
+	groups := map[string]*Builds{}
+	for _, b := range bs.Builds {
+		group, ok := groups[b.BuildTypeId]
+		if !ok {
+			group = &Builds{}
+			groups[b.BuildTypeId] = group
+		}
+		group.Builds = append(group.Builds, b)
+		group.Count++
+	}
+	return groups
+}
+
+// MostRecent returns the most recently triggered build, or nil if there are none
+func (bs *Builds) MostRecent() *Build {
+	return bs.first(func(byDate BuildsByDate, i, j int) bool { return byDate.Less(j, i) })
+}
+
+// OldestBuild returns the earliest triggered build, or nil if there are none
+func (bs *Builds) OldestBuild() *Build {
+	return bs.first(BuildsByDate.Less)
+}
+
+// first returns the build that would be sorted first by less, without reordering the builds
+func (bs *Builds) first(less func(byDate BuildsByDate, i, j int) bool) *Build {
+	if len(bs.Builds) == 0 {
+		return nil
+	}
+	byDate := BuildsByDate(bs.Builds)
+	first := 0
+	for i := 1; i < byDate.Len(); i++ {
+		if less(byDate, i, first) {
+			first = i
+		}
+	}
+	return &bs.Builds[first]
+}
+
+func (bs *Builds) filter(keep func(*Build) bool) *Builds {
+	filtered := &Builds{}
+	for i := range bs.Builds {
+		if keep(&bs.Builds[i]) {
+			filtered.Builds = append(filtered.Builds, bs.Builds[i])
+		}
+	}
+	filtered.Count = len(filtered.Builds)
+	return filtered
+}
+
 // Build is an instance of a stage in the build chain for a given project
 type Build struct {
 	Id              int             `json:"id,omitempty"`
 	Number          string          `json:"number,omitempty"`
 	BuildTypeId     string          `json:"buildTypeId,omitempty"`
 	BuildType       BuildType       `json:"buildType,omitempty"`
+	BranchName      string          `json:"branchName,omitempty"`
 	Status          string          `json:"status,omitempty"`
 	State           string          `json:"state,omitempty"`
 	Href            string          `json:"href,omitempty"`
@@ -37,6 +111,75 @@ type Build struct {
 	Properties      Params          `json:"properties,omitempty"`
 	WebUrl          string          `json:"webUrl,omitempty"`
 	BuildStatistics BuildStatistics `json:"statistics,omitempty"`
+	Pool            *AgentPool      `json:"agentPool,omitempty"`
+	Artifacts       *Artifacts      `json:"artifacts,omitempty"`
+	VcsLabels       []VcsLabel      `json:"vcsLabels,omitempty"`
+}
+
+// VcsLabel is a label applied in version control by a build
+type VcsLabel struct {
+	Text          string `json:"text,omitempty"`
+	Status        string `json:"status,omitempty"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// VcsLabels is a container for a list of VcsLabel's
+type VcsLabels struct {
+	Count     int        `json:"count,omitempty"`
+	VcsLabels []VcsLabel `json:"vcsLabel,omitempty"`
+}
+
+// Artifacts describes the artifacts published by a build
+type Artifacts struct {
+	Count int    `json:"count,omitempty"`
+	Href  string `json:"href,omitempty"`
+	Files []File `json:"file,omitempty"`
+}
+
+// File is an artifact file or directory published by a build
+type File struct {
+	Name             string `json:"name,omitempty"`
+	Size             int64  `json:"size,omitempty"`
+	Href             string `json:"href,omitempty"`
+	ContentHref      string `json:"-"`
+	ModificationTime Time   `json:"modificationTime,omitempty"`
+}
+
+// UnmarshalJSON unmarshals the file, flattening the href of its content
+func (f *File) UnmarshalJSON(data []byte) error {
+	type file File
+	v := struct {
+		*file
+		Content struct {
+			Href string `json:"href"`
+		} `json:"content"`
+	}{file: (*file)(f)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.ContentHref = v.Content.Href
+	return nil
+}
+
+// ArtifactsCount returns the number of artifacts the build published. TeamCity only includes the count
+// when it is requested, e.g. with a fields parameter of build(id,status,artifacts(count)).
+func (b *Build) ArtifactsCount() int {
+	if b.Artifacts == nil {
+		return 0
+	}
+	return b.Artifacts.Count
+}
+
+// buildCancelRequest is the body of a request to stop a running build
+type buildCancelRequest struct {
+	Comment        string `json:"comment,omitempty"`
+	ReaddIntoQueue bool   `json:"readdIntoQueue"`
+}
+
+// AgentPool is a group of build agents that builds can run in
+type AgentPool struct {
+	Id   int    `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
 }
 
 // BuildType is a type of Build
@@ -56,12 +199,178 @@ type BuildTypes struct {
 	BuildTypes []BuildType `json:"buildType,omitempty"`
 }
 
+// FindByID returns the build type with the given id, or nil if there is none
+func (bts *BuildTypes) FindByID(id string) *BuildType {
+	for i := range bts.BuildTypes {
+		if bts.BuildTypes[i].Id == id {
+			return &bts.BuildTypes[i]
+		}
+	}
+	return nil
+}
+
+// FindByName returns the first build type with the given name, or nil if there is none
+func (bts *BuildTypes) FindByName(name string) *BuildType {
+	for i := range bts.BuildTypes {
+		if bts.BuildTypes[i].Name == name {
+			return &bts.BuildTypes[i]
+		}
+	}
+	return nil
+}
+
+// BuildSteps is a container for a list of BuildStep's
+type BuildSteps struct {
+	Count int         `json:"count,omitempty"`
+	Steps []BuildStep `json:"step,omitempty"`
+}
+
+// BuildStep is a single runner step of a build type
+type BuildStep struct {
+	Id           string        `json:"id,omitempty"`
+	Name         string        `json:"name,omitempty"`
+	Type         string        `json:"type,omitempty"`
+	Disabled     bool          `json:"disabled,omitempty"`
+	Inherited    bool          `json:"inherited,omitempty"`
+	PropertyList *PropertyList `json:"properties,omitempty"`
+}
+
+// Features is a container for a list of Feature's
+type Features struct {
+	Count    int       `json:"count,omitempty"`
+	Features []Feature `json:"feature,omitempty"`
+}
+
+// Feature is a build feature of a build type, such as commit status publishing
+type Feature struct {
+	Id           string        `json:"id,omitempty"`
+	Type         string        `json:"type,omitempty"`
+	Disabled     bool          `json:"disabled,omitempty"`
+	Inherited    bool          `json:"inherited,omitempty"`
+	PropertyList *PropertyList `json:"properties,omitempty"`
+}
+
+// BuildTypeExport is the complete definition of a build type, suitable for backing it up or recreating it elsewhere
+type BuildTypeExport struct {
+	BuildType            BuildType             `json:"buildType"`
+	Settings             *PropertyList         `json:"settings,omitempty"`
+	Parameters           *Params               `json:"parameters,omitempty"`
+	Steps                *BuildSteps           `json:"steps,omitempty"`
+	Features             *Features             `json:"features,omitempty"`
+	Triggers             *Triggers             `json:"triggers,omitempty"`
+	SnapshotDependencies *SnapshotDependencies `json:"snapshotDependencies,omitempty"`
+	ArtifactDependencies *ArtifactDependencies `json:"artifactDependencies,omitempty"`
+	VcsRootEntries       *VcsRootEntries       `json:"vcsRootEntries,omitempty"`
+}
+
 // Dependency is a build type's artifact or snapshot dependency
 type Dependency struct {
 	Id              string        `json:"id,omitempty"`
 	Type            string        `json:"type,omitempty"`
 	SourceBuildType BuildType     `json:"source-buildType,omitempty"`
 	PropertyList    *PropertyList `json:"properties,omitempty"`
+	Inherited       bool          `json:"inherited,omitempty"`
+}
+
+// Snapshot dependency property names
+const (
+	runOnSameAgentProperty     = "run-build-on-the-same-agent"
+	onFailedDependencyProperty = "run-build-if-dependency-failed"
+	syncRevisionsProperty      = "sync-revisions"
+)
+
+// Actions to take when a snapshot dependency failed, for use with SetOnFailedDependency
+const (
+	OnFailedDependencyRun             = "RUN"
+	OnFailedDependencyRunAddProblem   = "RUN_ADD_PROBLEM"
+	OnFailedDependencyMakeFailedStart = "MAKE_FAILED_TO_START"
+	OnFailedDependencyCancel          = "CANCEL"
+)
+
+// SetRunOnSameAgent sets whether the snapshot dependency's builds must run on the same agent
+func (d *Dependency) SetRunOnSameAgent(b bool) {
+	d.setProperty(runOnSameAgentProperty, strconv.FormatBool(b))
+}
+
+// SetOnFailedDependency sets what to do when the snapshot dependency failed, one of the OnFailedDependency constants
+func (d *Dependency) SetOnFailedDependency(action string) {
+	d.setProperty(onFailedDependencyProperty, action)
+}
+
+// SetSyncRevisions sets whether the snapshot dependency's builds must use the same revisions
+func (d *Dependency) SetSyncRevisions(b bool) {
+	d.setProperty(syncRevisionsProperty, strconv.FormatBool(b))
+}
+
+func (d *Dependency) setProperty(name, value string) {
+	if d.PropertyList == nil {
+		d.PropertyList = &PropertyList{}
+	}
+	d.PropertyList.Set(name, value)
+}
+
+// Artifact dependency property names
+const (
+	pathRulesProperty        = "pathRules"
+	revisionNameProperty     = "revisionName"
+	revisionValueProperty    = "revisionValue"
+	cleanDestinationProperty = "cleanDestinationDirectory"
+)
+
+// Rules for which build of the source build type to take artifacts from, for use with SetRevisionRule
+const (
+	RevisionRuleLastSuccessful = "lastSuccessful"
+	RevisionRuleLastPinned     = "lastPinned"
+	RevisionRuleLastFinished   = "lastFinished"
+	RevisionRuleSameChain      = "sameChainOrLastFinished"
+	revisionRuleBuildNumber    = "buildNumber"
+)
+
+// SetPathRules sets the artifact dependency's newline separated path rules, e.g. dist/*.zip => lib
+func (d *Dependency) SetPathRules(rules string) {
+	d.setProperty(pathRulesProperty, rules)
+}
+
+// SetRevisionRule sets which build the artifact dependency takes artifacts from, one of the RevisionRule constants
+func (d *Dependency) SetRevisionRule(rule string) {
+	d.setProperty(revisionNameProperty, rule)
+	d.setProperty(revisionValueProperty, "latest."+rule)
+}
+
+// SetCleanDestination sets whether the destination directory is cleaned before downloading artifacts
+func (d *Dependency) SetCleanDestination(b bool) {
+	d.setProperty(cleanDestinationProperty, strconv.FormatBool(b))
+}
+
+// SetBuildNumber makes the artifact dependency take artifacts from the build with the given build number
+func (d *Dependency) SetBuildNumber(number string) {
+	d.setProperty(revisionNameProperty, revisionRuleBuildNumber)
+	d.setProperty(revisionValueProperty, number)
+}
+
+// ArtifactDownload is an artifact a build downloaded through an artifact dependency
+type ArtifactDownload struct {
+	SourceBuild  *Build
+	ArtifactPath string
+	// DownloadURL is empty when ArtifactPath is a pattern that could not be resolved to single artifacts
+	DownloadURL string
+}
+
+// artifactPaths returns the source paths of the dependency's path rules, ignoring exclusions and destinations
+func (d *Dependency) artifactPaths() []string {
+	var paths []string
+	for _, rule := range strings.Split(d.PropertyList.Value(pathRulesProperty), "\n") {
+		rule = strings.TrimSpace(rule)
+		if len(rule) == 0 || strings.HasPrefix(rule, "-:") {
+			continue
+		}
+		rule = strings.TrimPrefix(rule, "+:")
+		if i := strings.Index(rule, "=>"); i >= 0 {
+			rule = rule[:i]
+		}
+		paths = append(paths, strings.TrimSpace(rule))
+	}
+	return paths
 }
 
 type ArtifactDependencies struct {
@@ -95,6 +404,18 @@ func (pl *PropertyList) Value(name string) string {
 	return ""
 }
 
+// Set sets the named property's value, adding the property if not found.
+func (pl *PropertyList) Set(name, value string) {
+	for i, v := range pl.Properties {
+		if v.Name == name {
+			pl.Properties[i].Value = value
+			return
+		}
+	}
+	pl.Properties = append(pl.Properties, Property{Name: name, Value: value})
+	pl.Count = len(pl.Properties)
+}
+
 // Bool returns the named property's boolean value, or false if not found.
 func (pl *PropertyList) Bool(name string) bool {
 	if pl == nil {
@@ -118,6 +439,39 @@ type Changes struct {
 	Changes []Change `json:"change"`
 }
 
+// GetByVersion returns the first Change with the given version, or nil if none match
+func (c *Changes) GetByVersion(version string) *Change {
+	for i := range c.Changes {
+		if c.Changes[i].Version == version {
+			return &c.Changes[i]
+		}
+	}
+	return nil
+}
+
+// GetByUsername returns all changes made by the given user
+func (c *Changes) GetByUsername(username string) []*Change {
+	var changes []*Change
+	for i := range c.Changes {
+		if c.Changes[i].Username == username {
+			changes = append(changes, &c.Changes[i])
+		}
+	}
+	return changes
+}
+
+// FilterByDate returns the changes made at or after from and before to
+func (c *Changes) FilterByDate(from, to time.Time) Changes {
+	var changes []Change
+	for _, change := range c.Changes {
+		date := time.Time(change.Date)
+		if !date.Before(from) && date.Before(to) {
+			changes = append(changes, change)
+		}
+	}
+	return Changes{Changes: changes}
+}
+
 // GetChange returns the most relevant Change describing the build, prioritizing
 // Build.Changes over Build.LastChanges out of preference for changes to non-TeamCity repos
 func (b *Build) GetChange() Change {
@@ -132,11 +486,40 @@ func (b *Build) GetChange() Change {
 
 // Change is an individual change in a group that corresponds to a certain build
 type Change struct {
-	Id       int    `json:"id,omitempty"`
-	Version  string `json:"version,omitempty"`
-	Username string `json:"username,omitempty"`
-	Date     Time   `json:"date,omitempty"`
-	Comment  string `json:"comment,omitempty"`
+	Id       int          `json:"id,omitempty"`
+	Version  string       `json:"version,omitempty"`
+	Username string       `json:"username,omitempty"`
+	Date     Time         `json:"date,omitempty"`
+	Comment  string       `json:"comment,omitempty"`
+	Files    *ChangeFiles `json:"files,omitempty"`
+}
+
+// ChangeFiles is the list of files modified by a Change
+type ChangeFiles struct {
+	Count int          `json:"count,omitempty"`
+	Files []ChangeFile `json:"file,omitempty"`
+}
+
+// ChangeFile is a single file modified by a Change.
+// ChangeType is one of "added", "edited", "removed", "copied" or "unchanged".
+type ChangeFile struct {
+	File           string `json:"file,omitempty"`
+	RelativeFile   string `json:"relative-file,omitempty"`
+	ChangeType     string `json:"changeType,omitempty"`
+	BeforeRevision string `json:"before-revision,omitempty"`
+	AfterRevision  string `json:"after-revision,omitempty"`
+}
+
+// CountByType returns the number of files for each change type
+func (cf *ChangeFiles) CountByType() map[string]int {
+	counts := map[string]int{}
+	if cf == nil {
+		return counts
+	}
+	for _, f := range cf.Files {
+		counts[f.ChangeType]++
+	}
+	return counts
 }
 
 // GetShortVersion returns the first 8 characters of the change version
@@ -165,10 +548,9 @@ type SnapshotDependencies struct {
 	SnapshotDependencies []SnapshotDependency `json:"snapshot-dependency,omitempty"`
 }
 
-// SnapshotDependency relates a build type to its source build type
-type SnapshotDependency struct {
-	SourceBuildType BuildType `json:"source-buildType,omitempty"`
-}
+// SnapshotDependency relates a build type to its source build type. It is a Dependency, so its
+// settings, e.g. whether to run on the same agent, are kept in its PropertyList.
+type SnapshotDependency = Dependency
 
 // VcsRootEntries is a list of VcsRootEntry
 type VcsRootEntries struct {
@@ -177,17 +559,54 @@ type VcsRootEntries struct {
 
 // VcsRootEntry is a version control system entry for a build type
 type VcsRootEntry struct {
-	Id      string  `json:"id,omitempty"`
-	VcsRoot VcsRoot `json:"vcs-root,omitempty"`
+	Id            string  `json:"id,omitempty"`
+	VcsRoot       VcsRoot `json:"vcs-root,omitempty"`
+	CheckoutRules string  `json:"checkout-rules,omitempty"`
+	Inherited     bool    `json:"inherited,omitempty"`
+}
+
+// VcsRoots is a list of VcsRoot
+type VcsRoots struct {
+	Count    int       `json:"count,omitempty"`
+	Href     string    `json:"href,omitempty"`
+	NextHref string    `json:"nextHref,omitempty"`
+	VcsRoots []VcsRoot `json:"vcs-root,omitempty"`
 }
 
 // VcsRoot is a the id, name and properties of a version control system
 type VcsRoot struct {
 	Id           string        `json:"id,omitempty"`
 	Name         string        `json:"name,omitempty"`
+	VcsName      string        `json:"vcsName,omitempty"`
+	Project      *Project      `json:"project,omitempty"`
 	PropertyList *PropertyList `json:"properties,omitempty"`
 }
 
+// VcsRootInstances is a list of VcsRootInstance
+type VcsRootInstances struct {
+	VcsRootInstances []VcsRootInstance `json:"vcs-root-instance,omitempty"`
+}
+
+// VcsRootInstance is a VcsRoot resolved for a particular build type, along with the latest revision fetched for it
+type VcsRootInstance struct {
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	VcsRootId   string `json:"vcs-root-id,omitempty"`
+	LastVersion string `json:"lastVersion,omitempty"`
+}
+
+// Revisions is a list of Revision
+type Revisions struct {
+	Revisions []Revision `json:"revision,omitempty"`
+}
+
+// Revision is the version of a VCS root instance a build used
+type Revision struct {
+	Version         string          `json:"version,omitempty"`
+	VcsRootInstance VcsRootInstance `json:"vcs-root-instance,omitempty"`
+}
+
+// BuildStatistics is the list of metrics TeamCity collected for a build, such as its duration and test counts
 type BuildStatistics struct {
 	StatisticsEntries []StatisticsEntry `json:"property,omitempty"`
 }
@@ -198,20 +617,43 @@ type StatisticsEntry struct {
 	Value string `json:"value,omitempty"`
 }
 
-type Tag struct {
-	Name string `json:"name,omitempty"`
+// Names of commonly used build statistics
+const (
+	StatBuildDuration    = "BuildDuration"
+	StatTimeSpentInQueue = "TimeSpentInQueue"
+	StatTotalTestCount   = "TotalTestCount"
+	StatPassedTestCount  = "PassedTestCount"
+	StatFailedTestCount  = "FailedTestCount"
+)
+
+// Value returns the raw value of the statistic with the given name, or "" if the build does not report it
+func (s BuildStatistics) Value(name string) string {
+	for _, entry := range s.StatisticsEntries {
+		if entry.Name == name {
+			return entry.Value
+		}
+	}
+	return ""
+}
+
+// Int returns the value of the statistic with the given name as an integer, or 0 if it is missing
+func (s BuildStatistics) Int(name string) int64 {
+	v, _ := strconv.ParseInt(s.Value(name), 10, 64)
+	return v
 }
 
-type Tags struct {
-	Tags []Tag `json:"tag,omitempty"`
+// Duration returns how long the build ran, which TeamCity reports in milliseconds
+func (s BuildStatistics) Duration() time.Duration {
+	return time.Duration(s.Int(StatBuildDuration)) * time.Millisecond
 }
 
-func NewTags(t []string) *Tags {
-	tags := []Tag{}
-	for _, tag := range t {
-		tags = append(tags, Tag{Name: tag})
+// TestPassRate returns the fraction of tests that passed, between 0 and 1, or 0 if the build ran no tests
+func (s BuildStatistics) TestPassRate() float64 {
+	total := s.Int(StatTotalTestCount)
+	if total == 0 {
+		return 0
 	}
-	return &Tags{Tags: tags}
+	return float64(s.Int(StatPassedTestCount)) / float64(total)
 }
 
 // Triggered describes what triggered a particular build
@@ -220,9 +662,14 @@ type Triggered struct {
 	User User `json:"user,omitempty"`
 }
 
-// User describes a user on TeamCity
-type User struct {
-	Username string `json:"username,omitempty"`
+// BuildCause describes what caused a build to run.
+// Type is one of e.g. "vcs", "schedule", "buildType", "user" or "unknown".
+type BuildCause struct {
+	Type    string  `json:"type,omitempty"`
+	Build   *Build  `json:"build,omitempty"`
+	Change  *Change `json:"change,omitempty"`
+	User    *User   `json:"user,omitempty"`
+	Details string  `json:"details,omitempty"`
 }
 
 // Time is the date in the format TeamCity provides
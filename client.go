@@ -2,6 +2,7 @@ package teamcity
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -9,15 +10,49 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"path"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/yext/teamcity/locate"
 )
 
 var Logger = log.New(ioutil.Discard, "", 0)
 
+// ErrTagConflict is returned when tags keep changing concurrently while being updated
+var ErrTagConflict = errors.New("teamcity: tags modified concurrently")
+
+// ErrNotFound is returned when the requested entity does not exist
+var ErrNotFound = errors.New("teamcity: not found")
+
+// ErrBuildFinished is returned when trying to cancel a build that already finished
+var ErrBuildFinished = errors.New("teamcity: build already finished")
+
+// ErrNoBuild is returned when a build type has no builds yet
+var ErrNoBuild = errors.New("teamcity: no build found")
+
+// ErrHashNotAvailable is returned when TeamCity does not provide a checksum for an artifact
+var ErrHashNotAvailable = errors.New("teamcity: artifact hash not available")
+
+// ErrParameterNotFound is returned when the requested parameter does not exist
+var ErrParameterNotFound = errors.New("teamcity: parameter not found")
+
+// ErrNoDedupeKey is returned by TriggerBuildIfAbsent when no dedupe key is given, which would match
+// every queued or running build without a branch name
+var ErrNoDedupeKey = errors.New("teamcity: dedupe key is required")
+
+// ErrNoBuildType is returned when a build to trigger has neither BuildTypeId nor BuildType.Id set
+var ErrNoBuildType = errors.New("teamcity: build type id is required")
+
+// ErrNoDependencyId is returned when deleting a dependency without an id, which would otherwise
+// address the whole list of dependencies
+var ErrNoDependencyId = errors.New("teamcity: dependency id is required")
+
 const (
 	basePathSuffix         = "/httpAuth/app/rest/"
 	projectsPath           = "projects"
@@ -26,21 +61,61 @@ const (
 	buildQueuePath         = "buildQueue"
 	changesPath            = "changes"
 	parametersPath         = "parameters"
+	valuePath              = "value"
 	templatePath           = "template"
 	statsPath              = "statistics"
 	artifactDependencyPath = "artifact-dependencies"
 	snapshotDependencyPath = "snapshot-dependencies"
 	triggerPath            = "triggers"
 	vcsRootsPath           = "vcs-roots"
+	vcsRootInstancesPath   = "vcs-root-instances"
+	vcsRootEntriesPath     = "vcs-root-entries"
 	tagsPath               = "tags"
+	settingsPath           = "settings"
+	usersPath              = "users"
+	licensingDataPath      = "server/licensingData"
+	keystorePath           = "server/keystore"
+	groupsPath             = "userGroups"
+	agentPoolPath          = "agentPool"
+	featuresPath           = "features"
+	triggeredPath          = "triggered"
+	revisionsPath          = "revisions"
+	commentPath            = "comment"
+	vcsLabelsPath          = "vcsLabels"
+	cancelRequestPath      = "cancelRequest"
+	pinPath                = "pin"
+	propertiesPath         = "properties"
+	stepsPath              = "steps"
+	artifactsPath          = "artifacts"
+	artifactContentPath    = "content"
+	artifactMetadataPath   = "metadata"
+	artifactChildrenPath   = "children"
+	artifactArchivePath    = "archived"
+	archivedPath           = "archived"
+	parentProjectPath      = "parentProject"
+	testOccurrencesPath    = "testOccurrences"
+	pausedPath             = "paused"
 
 	locatorParamKey = "?locator="
+	restPathPrefix  = "/app/rest/"
+	guestPathPrefix = "/guestAuth/app/rest/"
 
 	artifactDependencyType = "artifact_dependency"
 	snapshotDependencyType = "snapshot_dependency"
 
-	jsonContentType = "application/json"
-	textContentType = "text/plain"
+	buildNumberPatternSetting = "buildNumberPattern"
+	branchFilterProperty      = "branchFilter"
+
+	maxTagUpdateAttempts  = 5
+	maxConcurrentRequests = 8
+	maxBackoff            = 30 * time.Second
+	defaultPollInterval   = 10 * time.Second
+	defaultPageSize       = 100
+
+	jsonContentType   = "application/json"
+	textContentType   = "text/plain"
+	binaryContentType = "application/octet-stream"
+	anyContentType    = "*/*"
 )
 
 // Client is an http client and authorization details used to make http requests to TeamCity's API
@@ -49,6 +124,17 @@ type Client struct {
 	host       string
 	username   string
 	password   string
+	token      string
+
+	maxAttempts    int
+	initialBackoff time.Duration
+
+	// logger overrides the package level Logger when set
+	logger *log.Logger
+	// timeout is applied to httpClient once all options of NewClientWithOptions ran
+	timeout time.Duration
+	// guest sends requests through the guest path without credentials
+	guest bool
 }
 
 // NewClient creates a new Client with specified authorization details
@@ -61,10 +147,63 @@ func NewClient(host, username, password string) *Client {
 	}
 }
 
+// NewTokenClient creates a new Client that authenticates with the specified access token
+func NewTokenClient(host, token string) *Client {
+	return &Client{
+		httpClient: http.DefaultClient,
+		host:       host,
+		token:      token,
+	}
+}
+
+// NewClientWithToken creates a new Client that authenticates with the specified access token.
+// It is equivalent to NewTokenClient.
+func NewClientWithToken(host, token string) *Client {
+	return NewTokenClient(host, token)
+}
+
 // ListProjects gets a list of all projects
 func (c *Client) ListProjects() (*Projects, error) {
+	return c.ListProjectsContext(context.Background())
+}
+
+// ListProjectsContext gets a list of all projects using the provided context
+func (c *Client) ListProjectsContext(ctx context.Context) (*Projects, error) {
 	v := &Projects{}
-	if err := c.doRequest("GET", projectsPath, "", nil, v); err != nil {
+	if err := c.doRequestContext(ctx, "GET", projectsPath, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListAllProjects gets all projects, following NextHref until every page was fetched
+func (c *Client) ListAllProjects() (*Projects, error) {
+	v, err := c.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	for next := v.NextHref; len(next) > 0; {
+		page, err := c.ListProjectsPage(next)
+		if err != nil {
+			return nil, err
+		}
+		v.Projects = append(v.Projects, page.Projects...)
+		next = page.NextHref
+	}
+	v.Count = len(v.Projects)
+	v.NextHref = ""
+	return v, nil
+}
+
+// ListProjectsPage gets a page of projects from the href of a previous page's NextHref,
+// or the first page if href is empty
+func (c *Client) ListProjectsPage(href string) (*PaginatedProjects, error) {
+	v := &PaginatedProjects{}
+	p := projectsPath
+	if len(href) > 0 {
+		p = hrefPath(href)
+	}
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
@@ -92,260 +231,1343 @@ func (c *Client) SelectProjects(selector string) (*Projects, error) {
 	return v, nil
 }
 
-// SelectBuilds gets the build with the specified buildLocator.
-// See https://confluence.jetbrains.com/display/TCD9/REST+API#RESTAPI-BuildLocator
-// for more information about constructing buildLocator string.
-func (c *Client) SelectBuilds(selector string) (*Builds, error) {
-	v := &Builds{}
-	path := buildsPath + locatorParamKey + selector
-	if err := c.doRequest("GET", path, "", nil, v); err != nil {
+// GetSubProjectIDs gets the ids of the direct sub-projects of the specified project
+func (c *Client) GetSubProjectIDs(parentLocator string) ([]string, error) {
+	v := &Projects{}
+	p := projectsPath + locatorParamKey + "parentProject:(" + parentLocator + ")&fields=project(id)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
-	return v, nil
+	ids := make([]string, len(v.Projects))
+	for i, project := range v.Projects {
+		ids[i] = project.Id
+	}
+	return ids, nil
 }
 
-// BuildFromId gets the build details for the build with specified id
-func (c *Client) BuildFromID(id int) (*Build, error) {
-	v := &Build{}
-	if err := c.doRequest("GET", path.Join(buildsPath, locate.ById(strconv.Itoa(id)).String()), "", nil, v); err != nil {
-		return nil, err
+// IterateProjects streams all projects, fetching them pageSize at a time. The project channel is closed
+// once all projects were sent or iteration stopped; any error, including cancellation of ctx, is then
+// sent on the error channel.
+func (c *Client) IterateProjects(ctx context.Context, pageSize int) (<-chan Project, <-chan error) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
 	}
-	return v, nil
+	projects := make(chan Project)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(projects)
+		for start := 0; ; start += pageSize {
+			if err := ctx.Err(); err != nil {
+				errs <- err
+				return
+			}
+			page := &Projects{}
+			p := projectsPath + locatorParamKey + fmt.Sprintf("start:%d,count:%d", start, pageSize)
+			if err := c.doRequestContext(ctx, "GET", p, "", nil, page); err != nil {
+				errs <- err
+				return
+			}
+			for _, project := range page.Projects {
+				select {
+				case projects <- project:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+			if len(page.Projects) < pageSize {
+				return
+			}
+		}
+	}()
+	return projects, errs
 }
 
-// SelectChange gets the Change with the specified selector
-func (c *Client) SelectChange(selector string) (*Change, error) {
-	v := &Change{}
-	if err := c.doRequest("GET", path.Join(changesPath, selector), "", nil, v); err != nil {
+// GetProjectRoleAssignments gets the roles held by users and groups that are effective on the
+// project with the specified locator, including global roles and roles inherited from parent projects.
+func (c *Client) GetProjectRoleAssignments(projectLocator string) ([]RoleAssignment, error) {
+	scopes, err := c.projectRoleScopes(projectLocator)
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
-}
 
-// SelectBuildType gets the build configuration with the specified selector
-func (c *Client) SelectBuildType(selector string) (*BuildType, error) {
-	v := &BuildType{}
-	if err := c.doRequest("GET", path.Join(buildTypesPath, selector), "", nil, v); err != nil {
+	users := &Users{}
+	if err := c.doRequest("GET", usersPath+"?fields=user(id,username,name,roles(role(roleId,scope)))", "", nil, users); err != nil {
 		return nil, err
 	}
-	return v, nil
+	groups := &Groups{}
+	if err := c.doRequest("GET", groupsPath+"?fields=group(key,name,roles(role(roleId,scope)))", "", nil, groups); err != nil {
+		return nil, err
+	}
+
+	var assignments []RoleAssignment
+	for i := range users.Users {
+		u := &users.Users[i]
+		for _, r := range u.Roles.effectiveIn(scopes) {
+			assignments = append(assignments, RoleAssignment{User: u, Role: r})
+		}
+	}
+	for i := range groups.Groups {
+		g := &groups.Groups[i]
+		for _, r := range g.Roles.effectiveIn(scopes) {
+			assignments = append(assignments, RoleAssignment{Group: g, Role: r})
+		}
+	}
+	return assignments, nil
 }
 
-// SelectBuildTypes gets the build configurations with the specified selector
-func (c *Client) SelectBuildTypes(selector string) (*BuildTypes, error) {
-	v := &BuildTypes{}
-	path := buildTypesPath + locatorParamKey + selector
-	if err := c.doRequest("GET", path, "", nil, v); err != nil {
+// CanEditProject reports whether the current user may edit the settings of the specified project,
+// based on the built-in roles assigned to the user and its groups. Custom roles are not considered.
+func (c *Client) CanEditProject(projectLocator string) (bool, error) {
+	scopes, err := c.projectRoleScopes(projectLocator)
+	if err != nil {
+		return false, err
+	}
+	u := &User{}
+	p := path.Join(usersPath, "current") + "?fields=roles(role(roleId,scope)),groups(group(key,roles(role(roleId,scope))))"
+	if err := c.doRequest("GET", p, "", nil, u); err != nil {
+		return false, err
+	}
+	roles := u.Roles.effectiveIn(scopes)
+	if u.Groups != nil {
+		for _, g := range u.Groups.Groups {
+			roles = append(roles, g.Roles.effectiveIn(scopes)...)
+		}
+	}
+	for _, r := range roles {
+		if projectEditRoles[r.RoleId] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// projectRoleScopes returns the role scopes that apply to the specified project:
+// the global scope, the project itself and each of its ancestors.
+func (c *Client) projectRoleScopes(projectLocator string) (map[string]bool, error) {
+	scopes := map[string]bool{globalRoleScope: true}
+	project, err := c.SelectProject(projectLocator)
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	for {
+		scopes[projectRoleScope(project.Id)] = true
+		if len(project.ParentProjectId) == 0 {
+			return scopes, nil
+		}
+		if project, err = c.SelectProject(locate.ById(project.ParentProjectId).String()); err != nil {
+			return nil, err
+		}
+	}
 }
 
-// SelectBuildTypeBuilds gets the builds belonging to the build configuration with the specified selector
-func (c *Client) SelectBuildTypeBuilds(selector string) (*Builds, error) {
+// SelectBuilds gets the build with the specified buildLocator.
+// See https://confluence.jetbrains.com/display/TCD9/REST+API#RESTAPI-BuildLocator
+// for more information about constructing buildLocator string.
+func (c *Client) SelectBuilds(selector fmt.Stringer) (*Builds, error) {
+	return c.SelectBuildsContext(context.Background(), selector)
+}
+
+// SelectBuildsContext gets the build with the specified buildLocator using the provided context
+func (c *Client) SelectBuildsContext(ctx context.Context, selector fmt.Stringer) (*Builds, error) {
+	return c.selectBuilds(ctx, selector, "")
+}
+
+// SelectBuildsWithFields gets the build with the specified buildLocator, limiting the response to the
+// given fields, e.g. build(id,number,status)
+func (c *Client) SelectBuildsWithFields(selector fmt.Stringer, fields string) (*Builds, error) {
+	return c.selectBuilds(context.Background(), selector, fields)
+}
+
+func (c *Client) selectBuilds(ctx context.Context, selector fmt.Stringer, fields string) (*Builds, error) {
 	v := &Builds{}
-	if err := c.doRequest("GET", path.Join(buildTypesPath, selector, buildsPath), "", nil, v); err != nil {
+	path := withFields(buildsPath+locatorParamKey+selector.String(), fields)
+	if err := c.doRequestContext(ctx, "GET", path, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-func (c *Client) SelectBuildStats(selector string) (*PropertyList, error) {
-	v := &PropertyList{}
-	if err := c.doRequest("GET", path.Join(buildsPath, selector, statsPath), "", nil, v); err != nil {
+// SelectBuildsPage gets a page of builds from the href of a previous page's NextHref
+func (c *Client) SelectBuildsPage(href string) (*Builds, error) {
+	v := &Builds{}
+	if err := c.doRequest("GET", hrefPath(href), "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// SelectVcsRoot gets the VcsRoot belonging to properties specified by the specified selector
-func (c *Client) SelectVcsRoot(selector string) (*VcsRoot, error) {
-	v := &VcsRoot{}
-	if err := c.doRequest("GET", path.Join(vcsRootsPath, selector), "", nil, v); err != nil {
+// SelectAllBuilds gets all builds with the specified buildLocator, following NextHref until every page was fetched
+func (c *Client) SelectAllBuilds(selector fmt.Stringer) (*Builds, error) {
+	return c.selectAllBuilds(selector, "")
+}
+
+// selectAllBuilds is SelectAllBuilds limited to the given fields, which have to include nextHref
+func (c *Client) selectAllBuilds(selector fmt.Stringer, fields string) (*Builds, error) {
+	v, err := c.selectBuilds(context.Background(), selector, fields)
+	if err != nil {
 		return nil, err
 	}
+	for next := v.NextHref; len(next) > 0; {
+		page, err := c.SelectBuildsPage(next)
+		if err != nil {
+			return nil, err
+		}
+		v.Builds = append(v.Builds, page.Builds...)
+		next = page.NextHref
+	}
+	v.Count = len(v.Builds)
+	v.NextHref = ""
 	return v, nil
 }
 
-// TriggerBuildID runs a build for the given build ID and change ID in TeamCity
-func (c *Client) TriggerBuildID(buildTypeId string, changeId int, pushDescription string) (*Build, error) {
-	return c.TriggerBuildIDWithProperties(buildTypeId, changeId, pushDescription, map[string]string{})
+// GetTestOccurrences gets the results of every test run by the build with the specified id,
+// following NextHref until every page was fetched
+func (c *Client) GetTestOccurrences(buildID int) ([]TestOccurrence, error) {
+	return c.SelectTestOccurrences(buildID, nil)
 }
 
-// TriggerBuildIDWithProperties runs a build for the given build ID and change ID in TeamCity, with the specified property values
-func (c *Client) TriggerBuildIDWithProperties(buildTypeId string, changeId int, pushDescription string, props map[string]string) (*Build, error) {
-	v := &Build{}
-	properties := []Property{
-		Property{
-			Name:  "env.PUSH_DESCRIPTION",
-			Value: pushDescription,
-		},
-		Property{
-			Name:  "reverse.dep.*.env.PUSH_DESCRIPTION",
-			Value: pushDescription,
-		},
-	}
-	for name, value := range props {
-		properties = append(properties, Property{
-			Name:  name,
-			Value: value,
-		})
-	}
-	build := &Build{
-		BuildType: BuildType{
-			Id: buildTypeId,
-		},
-		Properties: Params{
-			Properties: properties,
-		},
-	}
-	if changeId > 0 {
-		build.LastChanges = Changes{
-			Changes: []Change{
-				Change{Id: changeId},
-			},
-		}
+// SelectTestOccurrences is like GetTestOccurrences, but only gets the results of the tests matching
+// selector, e.g. locate.ByTestNameContains("Integration"). A nil selector matches every test.
+func (c *Client) SelectTestOccurrences(buildID int, selector fmt.Stringer) ([]TestOccurrence, error) {
+	locator := "build:(" + locate.ById(strconv.Itoa(buildID)).String() + ")," + locate.ByCount(defaultPageSize).String()
+	if selector != nil {
+		locator += "," + selector.String()
 	}
-	if len(pushDescription) > 0 {
-		build.Comment = Comment{
-			Text: pushDescription,
+	p := withFields(testOccurrencesPath+locatorParamKey+locator, "nextHref,testOccurrence(id,name,status,duration,details,ignoreDetails)")
+	var tests []TestOccurrence
+	for len(p) > 0 {
+		page := &TestOccurrences{}
+		if err := c.doRequest("GET", p, "", nil, page); err != nil {
+			return nil, err
 		}
+		tests = append(tests, page.TestOccurrences...)
+		p = hrefPath(page.NextHref)
 	}
-	if err := c.doJSONRequest("POST", buildQueuePath, build, v); err != nil {
+	return tests, nil
+}
+
+// GetBuildChainStatuses gets the status of every build in the snapshot dependency chain of the
+// specified build, including the build itself, keyed by build type id. Canceled, running, personal and
+// branch builds are included, since TeamCity's default filter would otherwise hide them.
+func (c *Client) GetBuildChainStatuses(buildLocator string) (map[string]string, error) {
+	selector := "snapshotDependency:(to:(" + buildLocator + ")," + locate.ByIncludeInitial(true).String() + ")," +
+		locate.ByDefaultFilter(false).String()
+	v, err := c.selectAllBuilds(locate.Raw(selector), "nextHref,build(id,buildTypeId,status)")
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	statuses := map[string]string{}
+	for _, b := range v.Builds {
+		statuses[b.BuildTypeId] = b.Status
+	}
+	return statuses, nil
 }
 
-// TriggerBuild runs a build using the given provided *Build.
-func (c *Client) TriggerBuild(build *Build, pushDescription string) (*Build, error) {
-	if len(pushDescription) > 0 {
-		build.Comment = Comment{Text: pushDescription}
+// GetDownstreamBuilds gets every build that depends on the specified build through snapshot dependencies,
+// including running, canceled, personal and branch builds that TeamCity's default filter would hide
+func (c *Client) GetDownstreamBuilds(buildLocator string) (*Builds, error) {
+	selector := "snapshotDependency:(from:(" + buildLocator + ")," + locate.ByIncludeInitial(false).String() + ")," +
+		locate.ByDefaultFilter(false).String()
+	return c.SelectAllBuilds(locate.Raw(selector))
+}
+
+// HasRunningBuilds reports whether any build matching the specified buildLocator is currently running,
+// e.g. HasRunningBuilds("affectedProject:(id:MyProject)"). Builds on every branch are considered unless
+// the selector has a branch dimension of its own.
+func (c *Client) HasRunningBuilds(selector string) (bool, error) {
+	v := &Builds{}
+	locator := "running:true,count:1"
+	if !hasDimension(selector, "branch") {
+		locator += ",branch:default:any"
 	}
-	if err := c.doJSONRequest("POST", buildQueuePath, build, build); err != nil {
-		return nil, err
+	if len(selector) > 0 {
+		locator = selector + "," + locator
 	}
-	return build, nil
+	if err := c.doRequest("GET", buildsPath+locatorParamKey+locator+"&fields=count", "", nil, v); err != nil {
+		return false, err
+	}
+	return v.Count > 0, nil
 }
 
-// UpdateParameter updates the parameter provided for the specified project name
-func (c *Client) UpdateParameter(projectLocator string, property *Property) (*Property, error) {
-	p := path.Join(projectsPath, projectLocator, parametersPath, property.Name)
-	v := &Property{}
-	if err := c.doJSONRequest("PUT", p, property, v); err != nil {
+// GetLatestBuild gets the most recent build of the specified build type, or ErrNoBuild if it has none yet
+func (c *Client) GetLatestBuild(buildTypeLocator string) (*Build, error) {
+	v, err := c.SelectBuilds(locate.Raw("buildType:(" + buildTypeLocator + "),count:1"))
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	if len(v.Builds) == 0 {
+		return nil, ErrNoBuild
+	}
+	return &v.Builds[0], nil
 }
 
-// UpdateBuildTypeParameter updates the parameter provided for the specified build type
-func (c *Client) UpdateBuildTypeParameter(buildTypeLocator string, property *Property) (*Property, error) {
-	p := path.Join(buildTypesPath, buildTypeLocator, parametersPath, property.Name)
-	v := &Property{}
-	if err := c.doJSONRequest("PUT", p, property, v); err != nil {
+// BuildFromId gets the build details for the build with specified id
+func (c *Client) BuildFromID(id int) (*Build, error) {
+	return c.BuildFromIDContext(context.Background(), id)
+}
+
+// BuildFromIDContext gets the build details for the build with specified id using the provided context
+func (c *Client) BuildFromIDContext(ctx context.Context, id int) (*Build, error) {
+	v := &Build{}
+	if err := c.doRequestContext(ctx, "GET", path.Join(buildsPath, locate.ById(strconv.Itoa(id)).String()), "", nil, v); err != nil {
 		return nil, err
 	}
+	// TeamCity applies labels once a build finished, and not every server allows reading them,
+	// so they are only fetched for finished builds and a failure leaves them empty
+	if v.VcsLabels == nil && v.State == StateFinished {
+		if labels, err := c.getBuildVcsLabels(ctx, id); err != nil {
+			c.log().Println("reading VCS labels of build", id, "failed:", err)
+		} else {
+			v.VcsLabels = labels.VcsLabels
+		}
+	}
 	return v, nil
 }
 
-// CreateProject creates a new project
-func (c *Client) CreateProject(project *Project) (*Project, error) {
-	v := &Project{}
-	if err := c.doJSONRequest("POST", projectsPath, project, v); err != nil {
-		return nil, err
+// WaitForBuild polls the build with specified id every pollInterval until it finished or ctx is done,
+// returning the finished build. A pollInterval of zero or less polls every 10 seconds.
+func (c *Client) WaitForBuild(ctx context.Context, buildID int, pollInterval time.Duration) (*Build, error) {
+	return c.WaitForBuildWithCallback(ctx, buildID, pollInterval, nil)
+}
+
+// WaitForBuildWithCallback is like WaitForBuild, but calls progress with the build after every poll
+func (c *Client) WaitForBuildWithCallback(ctx context.Context, buildID int, pollInterval time.Duration, progress func(*Build)) (*Build, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		build, err := c.BuildFromIDContext(ctx, buildID)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(build)
+		}
+		if build.State == StateFinished {
+			return build, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-	return v, nil
 }
 
-// CreateBuildType creates a new build type under designated project
-func (c *Client) CreateBuildType(projectLocator string, buildType *BuildType) (*BuildType, error) {
-	v := &BuildType{}
-	p := path.Join(projectsPath, projectLocator, buildTypesPath)
-	if err := c.doJSONRequest("POST", p, buildType, v); err != nil {
-		return nil, err
+// GetBuildVcsLabel gets the text of the VCS label applied by the build with specified id,
+// or ErrNotFound if the build did not apply one
+func (c *Client) GetBuildVcsLabel(buildID int) (string, error) {
+	labels, err := c.getBuildVcsLabels(context.Background(), buildID)
+	if err != nil {
+		return "", err
 	}
-	return v, nil
+	if len(labels.VcsLabels) == 0 {
+		return "", ErrNotFound
+	}
+	return labels.VcsLabels[0].Text, nil
 }
 
-// SelectSnapshotDependency selects a snapshot dependency with given id
-func (c *Client) SelectSnapshotDependency(buildTypeSelector string, dependencyId string) (*Dependency, error) {
-	v := &Dependency{}
-	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath, dependencyId)
-	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+func (c *Client) getBuildVcsLabels(ctx context.Context, buildID int) (*VcsLabels, error) {
+	v := &VcsLabels{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), vcsLabelsPath)
+	if err := c.doRequestContext(ctx, "GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// SelectArtifactDependencies selects all artifact dependencies for the given build type
-func (c *Client) SelectArtifactDependencies(buildTypeSelector string) (*ArtifactDependencies, error) {
-	v := &ArtifactDependencies{}
-	p := path.Join(buildTypesPath, buildTypeSelector, artifactDependencyPath)
+// GetBuildAgentPool gets the agent pool the build with specified id ran in
+func (c *Client) GetBuildAgentPool(buildID int) (*AgentPool, error) {
+	v := &AgentPool{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), agentPoolPath)
 	if err := c.doRequest("GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// SelectSnapshotDependencies selects all snapshot dependencies for the given build type
-func (c *Client) SelectSnapshotDependencies(buildTypeSelector string) (*SnapshotDependencies, error) {
-	v := &SnapshotDependencies{}
-	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath)
+// GetBuildCause gets what caused the build with specified id to run
+func (c *Client) GetBuildCause(buildID int) (*BuildCause, error) {
+	v := &BuildCause{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), triggeredPath)
 	if err := c.doRequest("GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// DeleteSnapshotDependency deletes a snapshot dependency
-func (c *Client) DeleteSnapshotDependency(buildTypeSelector string, dependency *Dependency) error {
-	dependency.Type = snapshotDependencyType
-	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath, dependency.Id)
-	if err := c.doJSONRequest("DELETE", p, nil, nil); err != nil {
-		return err
+// GetBuildRevisionByVcsRoot gets the revision the build with specified id used for the VCS root instance
+// with the given locator, e.g. id:123 or vcsRoot:(id:MyRoot). It returns ErrNotFound if the build did not
+// use a VCS root instance matching the locator.
+func (c *Client) GetBuildRevisionByVcsRoot(buildID int, vcsRootLocator string) (string, error) {
+	instances := &VcsRootInstances{}
+	p := withFields(vcsRootInstancesPath+locatorParamKey+vcsRootLocator, "vcs-root-instance(id)")
+	if err := c.doRequest("GET", p, "", nil, instances); err != nil {
+		return "", err
 	}
-	return nil
+	ids := map[string]bool{}
+	for _, instance := range instances.VcsRootInstances {
+		ids[instance.Id] = true
+	}
+
+	v := &Revisions{}
+	p = path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), revisionsPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return "", err
+	}
+	for _, r := range v.Revisions {
+		if ids[r.VcsRootInstance.Id] {
+			return r.Version, nil
+		}
+	}
+	return "", ErrNotFound
 }
 
-// CreateSnapshotDependency creates a snapshot dependency
-func (c *Client) CreateSnapshotDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error) {
-	v := &Dependency{}
-	dependency.Type = snapshotDependencyType
-	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath)
-	if err := c.doJSONRequest("POST", p, dependency, v); err != nil {
+// GetBuildStopMessage gets the message left when the build with specified id was stopped
+func (c *Client) GetBuildStopMessage(buildID int) (string, error) {
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), commentPath)
+	return c.doTextRequest("GET", p, "")
+}
+
+// SelectChange gets the Change with the specified selector
+func (c *Client) SelectChange(selector string) (*Change, error) {
+	v := &Change{}
+	if err := c.doRequest("GET", path.Join(changesPath, selector), "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// CreateArtifactDependency creates a artifact dependency
-func (c *Client) CreateArtifactDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error) {
-	v := &Dependency{}
-	dependency.Type = artifactDependencyType
-	p := path.Join(buildTypesPath, buildTypeSelector, artifactDependencyPath)
-	if err := c.doJSONRequest("POST", p, dependency, v); err != nil {
+// GetChangeWithStats gets the Change with the specified locator along with the files it modified.
+// TeamCity does not report line counts, so each file only carries its change type and revisions.
+func (c *Client) GetChangeWithStats(changeLocator string) (*Change, error) {
+	v := &Change{}
+	p := path.Join(changesPath, changeLocator) + "?fields=id,version,username,date,comment,files(count,file(file,relative-file,changeType,before-revision,after-revision))"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
-// CreateTrigger creates a trigger for a build type
+// SelectBuildType gets the build configuration with the specified selector
+func (c *Client) SelectBuildType(selector string) (*BuildType, error) {
+	v := &BuildType{}
+	if err := c.doRequest("GET", path.Join(buildTypesPath, selector), "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetBuildTypeProjectID gets the id of the project the specified build type belongs to, without fetching the full build type
+func (c *Client) GetBuildTypeProjectID(buildTypeLocator string) (string, error) {
+	v := &struct {
+		Project struct {
+			Id string `json:"id"`
+		} `json:"project"`
+	}{}
+	p := path.Join(buildTypesPath, buildTypeLocator) + "?fields=project(id)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return "", err
+	}
+	return v.Project.Id, nil
+}
+
+// GetBuildTypeProject gets the project the specified build type belongs to
+func (c *Client) GetBuildTypeProject(buildTypeLocator string) (*Project, error) {
+	v := &struct {
+		Project *Project `json:"project"`
+	}{}
+	p := path.Join(buildTypesPath, buildTypeLocator) + "?fields=project(id,name,webUrl,parentProjectId,archived)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	if v.Project == nil {
+		return nil, ErrNotFound
+	}
+	return v.Project, nil
+}
+
+// SelectBuildTypes gets the build configurations with the specified selector
+func (c *Client) SelectBuildTypes(selector fmt.Stringer) (*BuildTypes, error) {
+	return c.SelectBuildTypesWithFields(selector, "")
+}
+
+// SelectBuildTypesWithFields gets the build configurations with the specified selector, limiting the
+// response to the given fields, e.g. buildType(id,name)
+func (c *Client) SelectBuildTypesWithFields(selector fmt.Stringer, fields string) (*BuildTypes, error) {
+	v := &BuildTypes{}
+	path := withFields(buildTypesPath+locatorParamKey+selector.String(), fields)
+	if err := c.doRequest("GET", path, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetProjectBuildTypes gets the build configurations of the specified project. When ownOnly is set only
+// the build types defined directly under the project are returned, otherwise those of its sub-projects are included.
+func (c *Client) GetProjectBuildTypes(projectLocator string, ownOnly bool) (*BuildTypes, error) {
+	if ownOnly {
+		return c.SelectBuildTypes(locate.Raw("project:(" + projectLocator + ")"))
+	}
+	return c.SelectBuildTypes(locate.Raw("affectedProject:(" + projectLocator + ")"))
+}
+
+// GetBuildTypeIDs gets the ids of the build types of the specified project without fetching the full build types
+func (c *Client) GetBuildTypeIDs(projectLocator string) ([]string, error) {
+	v := &BuildTypes{}
+	p := buildTypesPath + locatorParamKey + "project:(" + projectLocator + ")&fields=buildType(id)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(v.BuildTypes))
+	for i, bt := range v.BuildTypes {
+		ids[i] = bt.Id
+	}
+	return ids, nil
+}
+
+// GetProjectBuildStatus gets the aggregate status of the latest builds of every build type under the specified project.
+// It is StatusSuccess only if all of them succeeded, StatusFailure if any of them did not, and StatusUnknown
+// when there is nothing to go by.
+func (c *Client) GetProjectBuildStatus(projectLocator string) (string, error) {
+	buildTypes, err := c.GetProjectBuildTypes(projectLocator, false)
+	if err != nil {
+		return "", err
+	}
+	if len(buildTypes.BuildTypes) == 0 {
+		return StatusUnknown, nil
+	}
+	status := StatusSuccess
+	for _, bt := range buildTypes.BuildTypes {
+		build, err := c.GetLatestBuild(locate.ById(bt.Id).String())
+		if err == ErrNoBuild {
+			status = StatusUnknown
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if build.Status != StatusSuccess {
+			return StatusFailure, nil
+		}
+	}
+	return status, nil
+}
+
+// SelectBuildTypeBuilds gets the builds belonging to the build configuration with the specified selector
+func (c *Client) SelectBuildTypeBuilds(selector fmt.Stringer) (*Builds, error) {
+	v := &Builds{}
+	if err := c.doRequest("GET", path.Join(buildTypesPath, selector.String(), buildsPath), "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (c *Client) SelectBuildStats(selector string) (*PropertyList, error) {
+	v := &PropertyList{}
+	if err := c.doRequest("GET", path.Join(buildsPath, selector, statsPath), "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetBuildStatistics gets the statistics of the build with the specified id
+func (c *Client) GetBuildStatistics(buildID int) (BuildStatistics, error) {
+	v := BuildStatistics{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), statsPath)
+	if err := c.doRequest("GET", p, "", nil, &v); err != nil {
+		return BuildStatistics{}, err
+	}
+	return v, nil
+}
+
+// SelectVcsRoot gets the VcsRoot belonging to properties specified by the specified selector
+func (c *Client) SelectVcsRoot(selector string) (*VcsRoot, error) {
+	v := &VcsRoot{}
+	if err := c.doRequest("GET", path.Join(vcsRootsPath, selector), "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListVcsRoots gets a list of all VCS roots
+func (c *Client) ListVcsRoots() (*VcsRoots, error) {
+	v := &VcsRoots{}
+	if err := c.doRequest("GET", vcsRootsPath, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteVcsRoot deletes the VCS root with the specified selector
+func (c *Client) DeleteVcsRoot(selector string) error {
+	return c.doRequest("DELETE", path.Join(vcsRootsPath, selector), "", nil, nil)
+}
+
+// GetVcsRootByURL gets the first VCS root whose url property is repoURL, or ErrNotFound if there is none
+func (c *Client) GetVcsRootByURL(repoURL string) (*VcsRoot, error) {
+	v := &VcsRoots{}
+	p := vcsRootsPath + locatorParamKey + "property:(name:url,value:" + url.QueryEscape(repoURL) + ",matchType:equals)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	if len(v.VcsRoots) == 0 {
+		return nil, ErrNotFound
+	}
+	return c.SelectVcsRoot(locate.ById(v.VcsRoots[0].Id).String())
+}
+
+// GetBuildTypeRevisions gets the last fetched revision of each VCS root attached to the specified build type, keyed by VCS root id
+func (c *Client) GetBuildTypeRevisions(buildTypeLocator string) (map[string]string, error) {
+	v := &VcsRootInstances{}
+	p := vcsRootInstancesPath + locatorParamKey + "buildType:(" + buildTypeLocator + ")&fields=vcs-root-instance(id,name,vcs-root-id,lastVersion)"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	revisions := map[string]string{}
+	for _, instance := range v.VcsRootInstances {
+		revisions[instance.VcsRootId] = instance.LastVersion
+	}
+	return revisions, nil
+}
+
+// CreateVcsRoot creates a new VCS root. If root has no id, one is generated from its project and name.
+// IdExistsError is returned if a VCS root with the same id already exists.
+func (c *Client) CreateVcsRoot(root *VcsRoot) (*VcsRoot, error) {
+	if len(root.Id) == 0 {
+		var projectId string
+		if root.Project != nil {
+			projectId = root.Project.Id
+		}
+		root.Id = generateVcsRootId(projectId, root.Name)
+	}
+	if err := validateId(root.Id); err != nil {
+		return nil, err
+	}
+	if _, err := c.SelectVcsRoot(locate.ById(root.Id).String()); err == nil {
+		return nil, IdExistsError{Id: root.Id}
+	} else if !IsNotFound(err) {
+		return nil, err
+	}
+
+	v := &VcsRoot{}
+	if err := c.doJSONRequest("POST", vcsRootsPath, root, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateVcsRoot replaces the definition of the VCS root with the specified selector
+func (c *Client) UpdateVcsRoot(selector string, vcsRoot *VcsRoot) (*VcsRoot, error) {
+	v := &VcsRoot{}
+	if err := c.doJSONRequest("PUT", path.Join(vcsRootsPath, selector), vcsRoot, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SetVcsRootProperty sets a single property of the VCS root with the specified selector, e.g. branch
+func (c *Client) SetVcsRootProperty(selector, name, value string) error {
+	p := path.Join(vcsRootsPath, selector, propertiesPath, name)
+	_, err := c.doTextRequest("PUT", p, value)
+	return err
+}
+
+// TriggerBuildID runs a build for the given build ID and change ID in TeamCity
+func (c *Client) TriggerBuildID(buildTypeId string, changeId int, pushDescription string) (*Build, error) {
+	return c.TriggerBuildIDWithProperties(buildTypeId, changeId, pushDescription, map[string]string{})
+}
+
+// TriggerBuildIDWithProperties runs a build for the given build ID and change ID in TeamCity, with the specified property values
+func (c *Client) TriggerBuildIDWithProperties(buildTypeId string, changeId int, pushDescription string, props map[string]string) (*Build, error) {
+	v := &Build{}
+	properties := []Property{
+		Property{
+			Name:  "env.PUSH_DESCRIPTION",
+			Value: pushDescription,
+		},
+		Property{
+			Name:  "reverse.dep.*.env.PUSH_DESCRIPTION",
+			Value: pushDescription,
+		},
+	}
+	for name, value := range props {
+		properties = append(properties, Property{
+			Name:  name,
+			Value: value,
+		})
+	}
+	build := &Build{
+		BuildType: BuildType{
+			Id: buildTypeId,
+		},
+		Properties: Params{
+			Properties: properties,
+		},
+	}
+	if changeId > 0 {
+		build.LastChanges = Changes{
+			Changes: []Change{
+				Change{Id: changeId},
+			},
+		}
+	}
+	if len(pushDescription) > 0 {
+		build.Comment = Comment{
+			Text: pushDescription,
+		}
+	}
+	if err := c.doJSONRequest("POST", buildQueuePath, build, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// TriggerBuild runs a build using the given provided *Build.
+func (c *Client) TriggerBuild(build *Build, pushDescription string) (*Build, error) {
+	return c.TriggerBuildContext(context.Background(), build, pushDescription)
+}
+
+// TriggerBuildContext runs a build using the given provided *Build and context.
+// Cancelling ctx aborts the request to queue the build.
+func (c *Client) TriggerBuildContext(ctx context.Context, build *Build, pushDescription string) (*Build, error) {
+	if len(pushDescription) > 0 {
+		build.Comment = Comment{Text: pushDescription}
+	}
+	if err := c.doJSONRequestContext(ctx, "POST", buildQueuePath, build, build); err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
+// TriggerBuildIfAbsent runs the given *Build unless a build of the same build type is already queued or
+// running for dedupeKey, which is compared against the branch name and change version of those builds.
+// It returns the existing build and false when one is found, or the new build and true otherwise.
+// ErrNoDedupeKey is returned for an empty dedupeKey, and ErrNoBuildType if build has no build type id.
+func (c *Client) TriggerBuildIfAbsent(build *Build, dedupeKey string) (*Build, bool, error) {
+	if len(dedupeKey) == 0 {
+		return nil, false, ErrNoDedupeKey
+	}
+	buildTypeId := build.BuildTypeId
+	if len(buildTypeId) == 0 {
+		buildTypeId = build.BuildType.Id
+	}
+	if len(buildTypeId) == 0 {
+		return nil, false, ErrNoBuildType
+	}
+	buildType := locate.ByBuildType(locate.ById(buildTypeId)).String()
+	fields := "&fields=build(id,buildTypeId,number,branchName,state,status,href,webUrl,changes(change(id,version)),lastChanges(change(id,version)))"
+
+	queued := &Builds{}
+	if err := c.doRequest("GET", buildQueuePath+locatorParamKey+buildType+fields, "", nil, queued); err != nil {
+		return nil, false, err
+	}
+	running := &Builds{}
+	if err := c.doRequest("GET", buildsPath+locatorParamKey+buildType+",running:true,branch:default:any"+fields, "", nil, running); err != nil {
+		return nil, false, err
+	}
+
+	for _, existing := range append(queued.Builds, running.Builds...) {
+		change := existing.GetChange()
+		if existing.BranchName == dedupeKey || (len(change.Version) > 0 && change.Version == dedupeKey) {
+			existing := existing
+			return &existing, false, nil
+		}
+	}
+
+	v, err := c.TriggerBuild(build, "")
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// GetBuildEstimatedStartTime gets when the queued build with specified id is expected to start,
+// or nil if TeamCity has no estimate, e.g. because the build type has no history to estimate from
+func (c *Client) GetBuildEstimatedStartTime(buildID int) (*time.Time, error) {
+	v := &struct {
+		StartEstimate *Time `json:"startEstimate"`
+	}{}
+	p := path.Join(buildQueuePath, locate.ById(strconv.Itoa(buildID)).String()) + "?fields=startEstimate"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	if v.StartEstimate == nil {
+		return nil, nil
+	}
+	t := time.Time(*v.StartEstimate)
+	return &t, nil
+}
+
+// CancelBuild removes the build with specified id from the queue, or stops it if it is already running,
+// in which case reAddToQueue puts it back into the queue. ErrBuildFinished is returned for finished builds.
+func (c *Client) CancelBuild(buildID int, comment string, reAddToQueue bool) error {
+	buildLocator := locate.ById(strconv.Itoa(buildID)).String()
+	build := &Build{}
+	if err := c.doRequest("GET", path.Join(buildsPath, buildLocator)+"?fields=id,state", "", nil, build); err != nil {
+		return err
+	}
+	switch build.State {
+	case StateFinished:
+		return ErrBuildFinished
+	case StateQueued:
+		return c.doRequest("DELETE", path.Join(buildQueuePath, buildLocator), "", nil, nil)
+	default:
+		req := &buildCancelRequest{Comment: comment, ReaddIntoQueue: reAddToQueue}
+		return c.doJSONRequest("POST", path.Join(buildsPath, buildLocator, cancelRequestPath), req, nil)
+	}
+}
+
+// PinBuild pins the build with the specified locator so cleanup does not remove it
+func (c *Client) PinBuild(buildLocator, comment string) error {
+	p := path.Join(buildsPath, buildLocator, pinPath)
+	return c.doRequest("PUT", p, textContentType, []byte(comment), nil)
+}
+
+// UnpinBuild unpins the build with the specified locator
+func (c *Client) UnpinBuild(buildLocator string) error {
+	p := path.Join(buildsPath, buildLocator, pinPath)
+	return c.doRequest("DELETE", p, "", nil, nil)
+}
+
+// PinBuildID pins the build with the specified id so cleanup does not remove it
+func (c *Client) PinBuildID(buildID int, comment string) error {
+	return c.PinBuild(locate.ById(strconv.Itoa(buildID)).String(), comment)
+}
+
+// UnpinBuildID unpins the build with the specified id
+func (c *Client) UnpinBuildID(buildID int) error {
+	return c.UnpinBuild(locate.ById(strconv.Itoa(buildID)).String())
+}
+
+// IsBuildPinned reports whether the build with the specified locator is pinned
+func (c *Client) IsBuildPinned(buildLocator string) (bool, error) {
+	p := path.Join(buildsPath, buildLocator, pinPath)
+	pinned, err := c.doTextRequest("GET", p, "")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.TrimSpace(pinned))
+}
+
+// GetProjectParameters gets all parameters of the specified project, including those inherited from parent projects
+func (c *Client) GetProjectParameters(projectLocator string) (*Params, error) {
+	v := &Params{}
+	p := path.Join(projectsPath, projectLocator, parametersPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateParameter updates the parameter provided for the specified project name
+func (c *Client) UpdateParameter(projectLocator string, property *Property) (*Property, error) {
+	p := path.Join(projectsPath, projectLocator, parametersPath, property.Name)
+	v := &Property{}
+	if err := c.doJSONRequest("PUT", p, property, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReplaceProjectParameters replaces the entire parameter set of the specified project with params
+func (c *Client) ReplaceProjectParameters(projectLocator string, params *Params) (*Params, error) {
+	v := &Params{}
+	p := path.Join(projectsPath, projectLocator, parametersPath)
+	if err := c.doJSONRequest("PUT", p, params, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteProjectParameter deletes the named parameter from the specified project
+func (c *Client) DeleteProjectParameter(projectLocator, paramName string) error {
+	p := path.Join(projectsPath, projectLocator, parametersPath, paramName)
+	if err := c.doRequest("DELETE", p, "", nil, nil); err != nil {
+		if IsNotFound(err) {
+			return ErrParameterNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetBuildTypeParameters gets all parameters of the specified build type along with their specifications
+func (c *Client) GetBuildTypeParameters(buildTypeLocator string) (*Params, error) {
+	v := &Params{}
+	p := path.Join(buildTypesPath, buildTypeLocator, parametersPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateBuildTypeParameter updates the parameter provided for the specified build type
+func (c *Client) UpdateBuildTypeParameter(buildTypeLocator string, property *Property) (*Property, error) {
+	p := path.Join(buildTypesPath, buildTypeLocator, parametersPath, property.Name)
+	v := &Property{}
+	if err := c.doJSONRequest("PUT", p, property, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ResolveParameterAcrossBuildTypes gets the value of the named parameter for each of the specified build types,
+// keyed by build type locator. Lookups run concurrently; build types whose lookup fails are left out of the
+// result, and an error is only returned when every lookup failed.
+func (c *Client) ResolveParameterAcrossBuildTypes(name string, buildTypeLocators []string) (map[string]string, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		lastErr error
+		values  = map[string]string{}
+		sem     = make(chan struct{}, maxConcurrentRequests)
+	)
+	for _, locator := range buildTypeLocators {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(locator string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			p := path.Join(buildTypesPath, locator, parametersPath, name, valuePath)
+			value, err := c.doTextRequest("GET", p, "")
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				c.log().Println("resolving", name, "for", locator, "failed:", err)
+				lastErr = err
+				return
+			}
+			values[locator] = value
+		}(locator)
+	}
+	wg.Wait()
+	if len(values) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return values, nil
+}
+
+// ReplaceBuildTypeParameters replaces the entire parameter set of the specified build type with params
+func (c *Client) ReplaceBuildTypeParameters(buildTypeLocator string, params *Params) (*Params, error) {
+	v := &Params{}
+	p := path.Join(buildTypesPath, buildTypeLocator, parametersPath)
+	if err := c.doJSONRequest("PUT", p, params, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CreateProject creates a new project
+func (c *Client) CreateProject(project *Project) (*Project, error) {
+	v := &Project{}
+	if err := c.doJSONRequest("POST", projectsPath, project, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ArchiveProject archives the project with the specified locator
+func (c *Client) ArchiveProject(projectLocator string) error {
+	return c.setProjectArchived(projectLocator, true)
+}
+
+// UnarchiveProject restores the archived project with the specified locator
+func (c *Client) UnarchiveProject(projectLocator string) error {
+	return c.setProjectArchived(projectLocator, false)
+}
+
+func (c *Client) setProjectArchived(projectLocator string, archived bool) error {
+	p := path.Join(projectsPath, projectLocator, archivedPath)
+	_, err := c.doTextRequest("PUT", p, strconv.FormatBool(archived))
+	return err
+}
+
+// SetParentProject moves the project with the specified locator under a new parent project.
+// TeamCity rejects moves that would create a cycle, which is returned as an *APIError.
+func (c *Client) SetParentProject(projectLocator string, newParentLocator string) (*Project, error) {
+	v := &Project{}
+	p := path.Join(projectsPath, projectLocator, parentProjectPath)
+	if err := c.doJSONRequest("PUT", p, &Project{Locator: newParentLocator}, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CreateBuildType creates a new build type under designated project
+func (c *Client) CreateBuildType(projectLocator string, buildType *BuildType) (*BuildType, error) {
+	v := &BuildType{}
+	p := path.Join(projectsPath, projectLocator, buildTypesPath)
+	if err := c.doJSONRequest("POST", p, buildType, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetBuildTypeFeatureCount gets the number of build features configured for the specified build type
+func (c *Client) GetBuildTypeFeatureCount(buildTypeLocator string) (int, error) {
+	v := &struct {
+		Count int `json:"count"`
+	}{}
+	p := path.Join(buildTypesPath, buildTypeLocator, featuresPath) + "?fields=count"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return 0, err
+	}
+	return v.Count, nil
+}
+
+// SelectSnapshotDependency selects a snapshot dependency with given id
+func (c *Client) SelectSnapshotDependency(buildTypeSelector string, dependencyId string) (*Dependency, error) {
+	v := &Dependency{}
+	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath, dependencyId)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SelectArtifactDependencies selects all artifact dependencies for the given build type
+func (c *Client) SelectArtifactDependencies(buildTypeSelector string) (*ArtifactDependencies, error) {
+	v := &ArtifactDependencies{}
+	p := path.Join(buildTypesPath, buildTypeSelector, artifactDependencyPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SelectSnapshotDependencies selects all snapshot dependencies for the given build type
+func (c *Client) SelectSnapshotDependencies(buildTypeSelector string) (*SnapshotDependencies, error) {
+	v := &SnapshotDependencies{}
+	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteSnapshotDependency deletes a snapshot dependency
+func (c *Client) DeleteSnapshotDependency(buildTypeSelector string, dependency *Dependency) error {
+	if len(dependency.Id) == 0 {
+		return ErrNoDependencyId
+	}
+	dependency.Type = snapshotDependencyType
+	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath, dependency.Id)
+	if err := c.doJSONRequest("DELETE", p, nil, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteSnapshotDependencyID deletes the snapshot dependency with the given id
+func (c *Client) DeleteSnapshotDependencyID(buildTypeSelector, dependencyId string) error {
+	if len(dependencyId) == 0 {
+		return ErrNoDependencyId
+	}
+	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath, dependencyId)
+	return c.doRequest("DELETE", p, "", nil, nil)
+}
+
+// CreateSnapshotDependency creates a snapshot dependency
+func (c *Client) CreateSnapshotDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error) {
+	v := &Dependency{}
+	dependency.Type = snapshotDependencyType
+	p := path.Join(buildTypesPath, buildTypeSelector, snapshotDependencyPath)
+	if err := c.doJSONRequest("POST", p, dependency, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SetSnapshotDependencies makes the specified build type depend on exactly the given source build types,
+// creating and deleting snapshot dependencies as needed
+func (c *Client) SetSnapshotDependencies(buildTypeLocator string, sourceBuildTypeIds []string) error {
+	existing, err := c.SelectSnapshotDependencies(buildTypeLocator)
+	if err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range sourceBuildTypeIds {
+		wanted[id] = true
+	}
+	for _, dep := range existing.SnapshotDependencies {
+		if wanted[dep.SourceBuildType.Id] {
+			delete(wanted, dep.SourceBuildType.Id)
+			continue
+		}
+		if err := c.DeleteSnapshotDependency(buildTypeLocator, &Dependency{Id: dep.Id}); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range sourceBuildTypeIds {
+		if !wanted[id] {
+			continue
+		}
+		delete(wanted, id)
+		dep := &Dependency{SourceBuildType: BuildType{Id: id}}
+		if _, err := c.CreateSnapshotDependency(buildTypeLocator, dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteArtifactDependency deletes the artifact dependency with the given id
+func (c *Client) DeleteArtifactDependency(buildTypeSelector, dependencyId string) error {
+	if len(dependencyId) == 0 {
+		return ErrNoDependencyId
+	}
+	p := path.Join(buildTypesPath, buildTypeSelector, artifactDependencyPath, dependencyId)
+	return c.doRequest("DELETE", p, "", nil, nil)
+}
+
+// CreateArtifactDependency creates a artifact dependency
+func (c *Client) CreateArtifactDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error) {
+	v := &Dependency{}
+	dependency.Type = artifactDependencyType
+	p := path.Join(buildTypesPath, buildTypeSelector, artifactDependencyPath)
+	if err := c.doJSONRequest("POST", p, dependency, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CreateTrigger creates a trigger for a build type
 func (c *Client) CreateTrigger(buildTypeSelector string, trigger *Trigger) (*Trigger, error) {
 	p := path.Join(buildTypesPath, buildTypeSelector, triggerPath)
 	if err := c.doJSONRequest("POST", p, trigger, trigger); err != nil {
 		return nil, err
 	}
-	return trigger, nil
+	return trigger, nil
+}
+
+//SelectTriggers selects all triggers for the given build type
+func (c *Client) SelectTriggers(buildTypeSelector string) (*Triggers, error) {
+	v := &Triggers{}
+	p := path.Join(buildTypesPath, buildTypeSelector, triggerPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteTrigger deletes the trigger with the given id from the given build type
+func (c *Client) DeleteTrigger(buildTypeSelector, triggerId string) error {
+	p := path.Join(buildTypesPath, buildTypeSelector, triggerPath, triggerId)
+	return c.doRequest("DELETE", p, "", nil, nil)
+}
+
+// GetInheritedBuildSteps gets the build steps the specified build type inherits from its template
+func (c *Client) GetInheritedBuildSteps(buildTypeLocator string) (*BuildSteps, error) {
+	v := &BuildSteps{}
+	p := path.Join(buildTypesPath, buildTypeLocator, templatePath, stepsPath)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetBuildStepRunnerType gets the runner type of the specified build step, e.g. simpleRunner or Maven2
+func (c *Client) GetBuildStepRunnerType(buildTypeLocator, stepID string) (string, error) {
+	v := &BuildStep{}
+	p := path.Join(buildTypesPath, buildTypeLocator, stepsPath, stepID) + "?fields=type"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return "", err
+	}
+	return v.Type, nil
+}
+
+// GetBuildNumberFormat gets the build number format of the specified build type, e.g. 1.0.%build.counter%
+func (c *Client) GetBuildNumberFormat(buildTypeLocator string) (string, error) {
+	p := path.Join(buildTypesPath, buildTypeLocator, settingsPath, buildNumberPatternSetting)
+	return c.doTextRequest("GET", p, "")
+}
+
+// SetBuildNumberFormat sets the build number format of the specified build type
+func (c *Client) SetBuildNumberFormat(buildTypeLocator string, format string) (string, error) {
+	p := path.Join(buildTypesPath, buildTypeLocator, settingsPath, buildNumberPatternSetting)
+	return c.doTextRequest("PUT", p, format)
+}
+
+// GetBuildTypePaused reports whether the specified build type is paused
+func (c *Client) GetBuildTypePaused(buildTypeSelector string) (bool, error) {
+	paused, err := c.doTextRequest("GET", path.Join(buildTypesPath, buildTypeSelector, pausedPath), "")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.TrimSpace(paused))
+}
+
+// SetBuildTypePaused pauses or resumes the specified build type, then reads the state back to make sure
+// the change took effect
+func (c *Client) SetBuildTypePaused(buildTypeSelector string, paused bool) error {
+	p := path.Join(buildTypesPath, buildTypeSelector, pausedPath)
+	if _, err := c.doTextRequest("PUT", p, strconv.FormatBool(paused)); err != nil {
+		return err
+	}
+	actual, err := c.GetBuildTypePaused(buildTypeSelector)
+	if err != nil {
+		return err
+	}
+	if actual != paused {
+		return fmt.Errorf("teamcity: build type %v is still paused=%v", buildTypeSelector, actual)
+	}
+	return nil
+}
+
+// GetTriggerBranchFilter gets the branch filter of the specified trigger of a build type
+func (c *Client) GetTriggerBranchFilter(buildTypeLocator, triggerId string) (string, error) {
+	p := path.Join(buildTypesPath, buildTypeLocator, triggerPath, triggerId, propertiesPath, branchFilterProperty)
+	return c.doTextRequest("GET", p, "")
+}
+
+// SetTriggerBranchFilter sets the branch filter of the specified trigger of a build type, e.g. +:refs/heads/release/*
+func (c *Client) SetTriggerBranchFilter(buildTypeLocator, triggerId, filter string) error {
+	p := path.Join(buildTypesPath, buildTypeLocator, triggerPath, triggerId, propertiesPath, branchFilterProperty)
+	_, err := c.doTextRequest("PUT", p, filter)
+	return err
+}
+
+// ExportBuildType gets the complete definition of the specified build type
+func (c *Client) ExportBuildType(buildTypeLocator string) (*BuildTypeExport, error) {
+	bt, err := c.SelectBuildType(buildTypeLocator)
+	if err != nil {
+		return nil, err
+	}
+	export := &BuildTypeExport{
+		BuildType:            BuildType{Id: bt.Id, Name: bt.Name, Project: bt.Project, Template: bt.Template, Paused: bt.Paused},
+		Settings:             &PropertyList{},
+		Parameters:           &Params{},
+		Steps:                &BuildSteps{},
+		Features:             &Features{},
+		Triggers:             &Triggers{},
+		SnapshotDependencies: &SnapshotDependencies{},
+		ArtifactDependencies: &ArtifactDependencies{},
+		VcsRootEntries:       &VcsRootEntries{},
+	}
+	parts := map[string]interface{}{
+		settingsPath:           export.Settings,
+		parametersPath:         export.Parameters,
+		stepsPath:              export.Steps,
+		featuresPath:           export.Features,
+		triggerPath:            export.Triggers,
+		snapshotDependencyPath: export.SnapshotDependencies,
+		artifactDependencyPath: export.ArtifactDependencies,
+		vcsRootEntriesPath:     export.VcsRootEntries,
+	}
+	for sub, v := range parts {
+		if err := c.doRequest("GET", path.Join(buildTypesPath, buildTypeLocator, sub), "", nil, v); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
 }
 
-//SelectTriggers selects all triggers for the given build type
-func (c *Client) SelectTriggers(buildTypeSelector string) (*Triggers, error) {
-	v := &Triggers{}
-	p := path.Join(buildTypesPath, buildTypeSelector, triggerPath)
-	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+// ImportBuildType creates a build type under the specified project from an exported definition.
+// Settings, parameters, steps, features, triggers and dependencies that are inherited from the template are not recreated.
+func (c *Client) ImportBuildType(projectLocator string, export *BuildTypeExport) (*BuildType, error) {
+	bt, err := c.CreateBuildType(projectLocator, &BuildType{Id: export.BuildType.Id, Name: export.BuildType.Name})
+	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	btLocator := locate.ById(bt.Id).String()
+	if export.BuildType.Template != nil {
+		if _, err := c.ApplyTemplate(btLocator, locate.ById(export.BuildType.Template.Id).String()); err != nil {
+			return nil, err
+		}
+	}
+	if export.Settings != nil {
+		for _, setting := range export.Settings.Properties {
+			if _, err := c.doTextRequest("PUT", path.Join(buildTypesPath, btLocator, settingsPath, setting.Name), setting.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.Parameters != nil {
+		params := &Params{}
+		for _, param := range export.Parameters.Properties {
+			if !param.Inherited {
+				params.Properties = append(params.Properties, param)
+			}
+		}
+		if _, err := c.ReplaceBuildTypeParameters(btLocator, params); err != nil {
+			return nil, err
+		}
+	}
+	create := func(sub string, v interface{}) error {
+		return c.doJSONRequest("POST", path.Join(buildTypesPath, btLocator, sub), v, nil)
+	}
+	if export.VcsRootEntries != nil {
+		for _, entry := range export.VcsRootEntries.VcsRootEntries {
+			if entry.Inherited {
+				continue
+			}
+			if err := create(vcsRootEntriesPath, entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.Steps != nil {
+		for _, step := range export.Steps.Steps {
+			if step.Inherited {
+				continue
+			}
+			if err := create(stepsPath, step); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.Features != nil {
+		for _, feature := range export.Features.Features {
+			if feature.Inherited {
+				continue
+			}
+			if err := create(featuresPath, feature); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.Triggers != nil {
+		for _, trigger := range export.Triggers.Triggers {
+			if trigger.Inherited {
+				continue
+			}
+			if err := create(triggerPath, trigger); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.SnapshotDependencies != nil {
+		for _, dep := range export.SnapshotDependencies.SnapshotDependencies {
+			if dep.Inherited {
+				continue
+			}
+			dep := dep
+			if _, err := c.CreateSnapshotDependency(btLocator, &dep); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export.ArtifactDependencies != nil {
+		for _, dep := range export.ArtifactDependencies.ArtifactDependencies {
+			if dep.Inherited {
+				continue
+			}
+			dep := dep
+			if _, err := c.CreateArtifactDependency(btLocator, &dep); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.SelectBuildType(btLocator)
 }
 
 // ApplyTemplate applies a build type template to specified build type
@@ -358,6 +1580,25 @@ func (c *Client) ApplyTemplate(buildTypeSelector string, templateSelector string
 	return v, nil
 }
 
+// GetLicensingData gets the license limits of the server and their remaining capacity
+func (c *Client) GetLicensingData() (*LicensingData, error) {
+	v := &LicensingData{}
+	if err := c.doRequest("GET", licensingDataPath, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetServerPublicKey gets the DER encoded public key of the server, for validating its certificate out of band
+func (c *Client) GetServerPublicKey() ([]byte, error) {
+	body, err := c.doStreamRequest("GET", keystorePath, binaryContentType)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
 func (c *Client) GetTagByLocator(locator string) (*Tags, error) {
 	v := &Tags{}
 	p := path.Join(buildsPath, locator, tagsPath)
@@ -375,24 +1616,331 @@ func (c *Client) SetTagByLocator(locator string, tags *Tags) (*Tags, error) {
 	return tags, nil
 }
 
-func (c *Client) DownloadBuildLog(buildId int, w io.WriteCloser) error {
-	url := c.host + fmt.Sprintf("/downloadBuildLog.html?buildId=%d", buildId)
+// GetBuildTags gets the tags of the build with the specified id
+func (c *Client) GetBuildTags(buildID int) (*Tags, error) {
+	return c.GetTagByLocator(locate.ById(strconv.Itoa(buildID)).String())
+}
+
+// SetBuildTags replaces the tags of the build with the specified id
+func (c *Client) SetBuildTags(buildID int, tags *Tags) (*Tags, error) {
+	return c.SetTagByLocator(locate.ById(strconv.Itoa(buildID)).String(), tags)
+}
+
+// AddBuildTag adds tag to the build with the specified id, unless the build already has it
+func (c *Client) AddBuildTag(buildID int, tag string) (*Tags, error) {
+	tags, err := c.GetBuildTags(buildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags.Tags {
+		if t.Name == tag {
+			return tags, nil
+		}
+	}
+	tags.Tags = append(tags.Tags, Tag{Name: tag})
+	return c.SetBuildTags(buildID, tags)
+}
+
+// UpdateTags replaces the tags of the build with the specified locator with the result of fn.
+// TeamCity does not version tags, so concurrent writers cannot be excluded, only detected in part:
+// the tags are read again just before writing, and read back after writing, and the update is retried
+// when either differs, returning ErrTagConflict after several attempts. A write by another client that
+// lands between the second read and the write is still lost.
+func (c *Client) UpdateTags(buildLocator string, fn func(current []string) []string) error {
+	for attempt := 0; attempt < maxTagUpdateAttempts; attempt++ {
+		current, err := c.GetTagByLocator(buildLocator)
+		if err != nil {
+			return err
+		}
+		names := fn(current.Names())
+		latest, err := c.GetTagByLocator(buildLocator)
+		if err != nil {
+			return err
+		}
+		if !sameNames(current.Names(), latest.Names()) {
+			continue
+		}
+		if _, err := c.SetTagByLocator(buildLocator, NewTags(names)); err != nil {
+			return err
+		}
+		written, err := c.GetTagByLocator(buildLocator)
+		if err != nil {
+			return err
+		}
+		if sameNames(names, written.Names()) {
+			return nil
+		}
+	}
+	return ErrTagConflict
+}
+
+// sameNames reports whether a and b contain the same names, ignoring order and duplicates
+func sameNames(a, b []string) bool {
+	set := func(names []string) map[string]bool {
+		m := map[string]bool{}
+		for _, n := range names {
+			m[n] = true
+		}
+		return m
+	}
+	sa, sb := set(a), set(b)
+	if len(sa) != len(sb) {
+		return false
+	}
+	for n := range sa {
+		if !sb[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// ArtifactURL returns the URL to download the artifact at artifactPath of the build with the specified locator.
+// The URL uses the path prefix of the client's authentication, /httpAuth/app/rest/ for username and password,
+// /app/rest/ for tokens, or /guestAuth/app/rest/ with WithGuestAuth. It carries no credentials, so the consumer
+// fetching it needs its own unless the client uses guest access.
+func (c *Client) ArtifactURL(buildLocator, artifactPath string) string {
+	return c.host + c.basePath() + path.Join(buildsPath, buildLocator, artifactsPath, artifactContentPath, escapePath(artifactPath))
+}
+
+// ListArtifacts lists the top level artifacts of the build with the specified locator
+func (c *Client) ListArtifacts(buildLocator string) (*Artifacts, error) {
+	return c.ListArtifactChildren(buildLocator, "")
+}
+
+// ListArtifactChildren lists the artifacts in the directory at artifactPath of the build with the specified locator.
+// Each segment of artifactPath is URL-encoded.
+func (c *Client) ListArtifactChildren(buildLocator, artifactPath string) (*Artifacts, error) {
+	v := &Artifacts{}
+	p := path.Join(buildsPath, buildLocator, artifactsPath, artifactChildrenPath, escapePath(artifactPath))
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListArtifactsByPattern lists the artifacts in the directory at basePath of the build with specified id
+// whose names match pattern, e.g. *.log
+func (c *Client) ListArtifactsByPattern(buildID int, basePath, pattern string) (*Artifacts, error) {
+	v := &Artifacts{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), artifactsPath, artifactChildrenPath, escapePath(basePath)) +
+		locatorParamKey + "pattern:" + url.QueryEscape(pattern)
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DownloadArtifact streams the content of the artifact at artifactPath of the build with the specified locator.
+// Each segment of artifactPath is URL-encoded. The caller is responsible for closing the returned reader.
+func (c *Client) DownloadArtifact(buildLocator, artifactPath string) (io.ReadCloser, error) {
+	p := path.Join(buildsPath, buildLocator, artifactsPath, artifactContentPath, escapePath(artifactPath))
+	return c.doStreamRequest("GET", p, anyContentType)
+}
+
+// ListBuildArtifacts lists the artifacts in the directory at artifactPath of the build with specified id.
+// Each segment of artifactPath is URL-encoded.
+func (c *Client) ListBuildArtifacts(buildID int, artifactPath string) (*Artifacts, error) {
+	return c.ListArtifactChildren(locate.ById(strconv.Itoa(buildID)).String(), artifactPath)
+}
+
+// DownloadBuildArtifact streams the content of the artifact at artifactPath of the build with specified id.
+// Each segment of artifactPath is URL-encoded. The caller is responsible for closing the returned reader.
+func (c *Client) DownloadBuildArtifact(buildID int, artifactPath string) (io.ReadCloser, error) {
+	return c.DownloadArtifact(locate.ById(strconv.Itoa(buildID)).String(), artifactPath)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// StreamArtifactsZip writes the artifacts under basePath of the build with specified id to w as a zip archive.
+// The archive is copied to w as TeamCity sends it, without holding it in memory.
+func (c *Client) StreamArtifactsZip(buildID int, basePath string, w io.Writer) error {
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), artifactsPath, artifactArchivePath, escapePath(basePath))
+	body, err := c.doStreamRequest("GET", p, anyContentType)
 	if err != nil {
 		return err
 	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// GetArtifactHash gets the hex encoded SHA-256 checksum of the artifact at artifactPath of the build with
+// specified id, or ErrHashNotAvailable if TeamCity does not provide one for the artifact
+func (c *Client) GetArtifactHash(buildID int, artifactPath string) (string, error) {
+	v := &struct {
+		Sha256 string `json:"sha256"`
+	}{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), artifactsPath, artifactMetadataPath, escapePath(artifactPath)) + "?fields=sha256"
+	if err := c.doRequest("GET", p, "", nil, v); err != nil {
+		return "", err
+	}
+	if len(v.Sha256) == 0 {
+		return "", ErrHashNotAvailable
+	}
+	return v.Sha256, nil
+}
+
+// GetBuildDownloadableDependencies gets the artifacts the build with specified id downloads through its
+// artifact dependencies, resolved against the builds that were actually used as their sources.
+// The dependencies are those the build type has now, which may differ from the ones the build ran with.
+// Path rules with wildcards in their file name, e.g. dist/*.zip, are expanded against the artifacts of
+// the source build. Rules that cannot be expanded that way, e.g. dist/**/*.zip or paths inside archives
+// such as a.zip!/**, are returned with an empty DownloadURL.
+func (c *Client) GetBuildDownloadableDependencies(buildID int) ([]ArtifactDownload, error) {
+	build, err := c.BuildFromID(buildID)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := c.SelectArtifactDependencies(locate.ById(build.BuildTypeId).String())
+	if err != nil {
+		return nil, err
+	}
+	sources := &Builds{}
+	p := path.Join(buildsPath, locate.ById(strconv.Itoa(buildID)).String(), artifactDependencyPath)
+	if err := c.doRequest("GET", p, "", nil, sources); err != nil {
+		return nil, err
+	}
+
+	var downloads []ArtifactDownload
+	for _, dep := range deps.ArtifactDependencies {
+		for i := range sources.Builds {
+			source := &sources.Builds[i]
+			if source.BuildTypeId != dep.SourceBuildType.Id {
+				continue
+			}
+			for _, rule := range dep.artifactPaths() {
+				expanded, err := c.expandArtifactRule(source, rule)
+				if err != nil {
+					return nil, err
+				}
+				downloads = append(downloads, expanded...)
+			}
+		}
+	}
+	return downloads, nil
+}
+
+// expandArtifactRule resolves the source path of an artifact dependency path rule to the artifacts of source
+func (c *Client) expandArtifactRule(source *Build, rule string) ([]ArtifactDownload, error) {
+	sourceLocator := locate.ById(strconv.Itoa(source.Id)).String()
+	if strings.Contains(rule, "!") || strings.Contains(rule, "**") {
+		return []ArtifactDownload{{SourceBuild: source, ArtifactPath: rule}}, nil
+	}
+	if !strings.ContainsAny(rule, "*?") {
+		return []ArtifactDownload{{SourceBuild: source, ArtifactPath: rule, DownloadURL: c.ArtifactURL(sourceLocator, rule)}}, nil
+	}
+	dir, pattern := path.Split(rule)
+	if strings.ContainsAny(dir, "*?") {
+		return []ArtifactDownload{{SourceBuild: source, ArtifactPath: rule}}, nil
+	}
+	artifacts, err := c.ListArtifactsByPattern(source.Id, dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	var downloads []ArtifactDownload
+	for _, f := range artifacts.Files {
+		if len(f.ContentHref) == 0 {
+			// directories have no content
+			continue
+		}
+		artifactPath := path.Join(dir, f.Name)
+		downloads = append(downloads, ArtifactDownload{SourceBuild: source, ArtifactPath: artifactPath, DownloadURL: c.ArtifactURL(sourceLocator, artifactPath)})
+	}
+	return downloads, nil
+}
+
+// hasDimension reports whether the top level of locator has the named dimension, ignoring nested locators
+func hasDimension(locator, name string) bool {
+	var dimensions []string
+	depth, start := 0, 0
+	for i, r := range locator {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				dimensions = append(dimensions, locator[start:i])
+				start = i + 1
+			}
+		}
+	}
+	dimensions = append(dimensions, locator[start:])
+	for _, d := range dimensions {
+		if strings.HasPrefix(d, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// withFields appends the fields parameter to a path that already has a query string, if fields is set
+func withFields(p, fields string) string {
+	if len(fields) == 0 {
+		return p
+	}
+	return p + "&fields=" + url.QueryEscape(fields)
+}
+
+// hrefPath converts an href returned by TeamCity, such as /httpAuth/app/rest/builds?locator=start:100,
+// to a path relative to the REST API root
+func hrefPath(href string) string {
+	if i := strings.Index(href, restPathPrefix); i >= 0 {
+		return href[i+len(restPathPrefix):]
+	}
+	return strings.TrimPrefix(href, "/")
+}
+
+// escapePath escapes each segment of a slash separated path
+func escapePath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// GetBuildLog streams the log of the build with the specified locator.
+// The caller is responsible for closing the returned reader.
+func (c *Client) GetBuildLog(buildLocator string) (io.ReadCloser, error) {
+	build := &Build{}
+	if err := c.doRequest("GET", path.Join(buildsPath, buildLocator)+"?fields=id", "", nil, build); err != nil {
+		return nil, err
+	}
+	return c.GetBuildLogContext(context.Background(), build.Id)
+}
+
+// GetBuildLogContext streams the log of the build with the specified id. Cancelling ctx interrupts
+// the download, which is useful when following the log of a long running build.
+// The caller is responsible for closing the returned reader.
+func (c *Client) GetBuildLogContext(ctx context.Context, buildID int) (io.ReadCloser, error) {
+	return c.streamURL(ctx, "GET", c.buildLogURL(buildID), textContentType)
+}
+
+// GetBuildLogText returns the full log of the build with the specified id
+func (c *Client) GetBuildLogText(ctx context.Context, buildID int) (string, error) {
+	body, err := c.GetBuildLogContext(ctx, buildID)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
 
-	req.SetBasicAuth(c.username, c.password)
-	resp, err := c.httpClient.Do(req)
+func (c *Client) DownloadBuildLog(buildId int, w io.WriteCloser) error {
+	body, err := c.streamURL(context.Background(), "GET", c.buildLogURL(buildId), textContentType)
 	if err != nil {
 		return err
 	}
 
-	if _, err := io.Copy(w, resp.Body); err != nil {
+	if _, err := io.Copy(w, body); err != nil {
 		return err
 	}
-	if err := resp.Body.Close(); err != nil {
+	if err := body.Close(); err != nil {
 		return err
 	}
 	if err := w.Close(); err != nil {
@@ -403,46 +1951,36 @@ func (c *Client) DownloadBuildLog(buildId int, w io.WriteCloser) error {
 }
 
 func (c *Client) doJSONRequest(method, path string, t, v interface{}) error {
+	return c.doJSONRequestContext(context.Background(), method, path, t, v)
+}
+
+func (c *Client) doJSONRequestContext(ctx context.Context, method, path string, t, v interface{}) error {
 	body, err := json.Marshal(t)
 	if err != nil {
 		return err
 	}
-	if err := c.doRequest(method, path, jsonContentType, body, v); err != nil {
+	if err := c.doRequestContext(ctx, method, path, jsonContentType, body, v); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (c *Client) doRequest(method string, path string, contentType string, data []byte, v interface{}) error {
-	Logger.Println(method, path, "\nbody:\n", string(data))
-	url := c.host + basePathSuffix + path
-	var body io.Reader
-	if data != nil {
-		body = bytes.NewBuffer(data)
-	}
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return err
-	}
-
-	rawAuth := []byte(fmt.Sprintf("%v:%v", c.username, c.password))
-	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString(rawAuth))
-	req.Header.Set("Accept", "application/json")
-	if len(contentType) > 0 {
-		req.Header.Set("Content-Type", contentType)
-	} else {
-		req.Header.Set("Content-Type", jsonContentType)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	return c.doRequestContext(context.Background(), method, path, contentType, data, v)
+}
 
+func (c *Client) doRequestContext(ctx context.Context, method string, path string, contentType string, data []byte, v interface{}) error {
+	resp, err := c.sendRequest(ctx, method, path, contentType, jsonContentType, data)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return c.newAPIError(resp)
+	}
 	if v != nil {
 		b, _ := ioutil.ReadAll(resp.Body)
-		Logger.Println("response:\n", string(b))
+		c.log().Println("response:\n", string(b))
 		if json.Unmarshal(b, v) != nil {
 			return errors.New(string(b))
 		}
@@ -451,3 +1989,190 @@ func (c *Client) doRequest(method string, path string, contentType string, data
 
 	return nil
 }
+
+// doTextRequest sends a text/plain body and returns the text/plain response
+func (c *Client) doTextRequest(method string, path string, data string) (string, error) {
+	var body []byte
+	if len(data) > 0 {
+		body = []byte(data)
+	}
+	resp, err := c.sendRequest(context.Background(), method, path, textContentType, textContentType, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", c.newAPIError(resp)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	c.log().Println("response:\n", string(b))
+	return string(b), nil
+}
+
+// doStreamRequest returns the body of the response for the caller to read and close
+func (c *Client) doStreamRequest(method string, path string, accept string) (io.ReadCloser, error) {
+	return c.streamURL(context.Background(), method, c.host+c.basePath()+path, accept)
+}
+
+// streamURL is like doStreamRequest, but for any URL on the server rather than a REST API path
+func (c *Client) streamURL(ctx context.Context, method string, url string, accept string) (io.ReadCloser, error) {
+	resp, err := c.sendURLRequest(ctx, method, url, "", accept, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, c.newAPIError(resp)
+	}
+	return resp.Body, nil
+}
+
+// APIError is returned when TeamCity responds with an error status code
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	Path       string
+	// Message is the first line of Body, which usually explains what went wrong
+	Message string
+	// Body is the raw response body
+	Body string
+}
+
+func (c *Client) newAPIError(resp *http.Response) *APIError {
+	b, _ := ioutil.ReadAll(resp.Body)
+	c.log().Println("response:\n", string(b))
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Method:     resp.Request.Method,
+		Path:       resp.Request.URL.RequestURI(),
+		Message:    strings.TrimSpace(strings.SplitN(strings.TrimSpace(string(b)), "\n", 2)[0]),
+		Body:       string(b),
+	}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("teamcity: %v %v: %v: %v", e.Method, e.Path, e.Status, e.Message)
+}
+
+// IsNotFound reports whether err is an *APIError for a missing resource
+func IsNotFound(err error) bool {
+	return isStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an *APIError for a request with missing or invalid credentials
+func IsUnauthorized(err error) bool {
+	return isStatus(err, http.StatusUnauthorized)
+}
+
+func isStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+func (c *Client) sendRequest(ctx context.Context, method string, path string, contentType string, accept string, data []byte) (*http.Response, error) {
+	return c.sendURLRequest(ctx, method, c.host+c.basePath()+path, contentType, accept, data)
+}
+
+func (c *Client) sendURLRequest(ctx context.Context, method string, url string, contentType string, accept string, data []byte) (*http.Response, error) {
+	c.log().Println(method, url, "\nbody:\n", string(data))
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewBuffer(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		c.setAuthorization(req)
+		req.Header.Set("Accept", accept)
+		if len(contentType) > 0 {
+			req.Header.Set("Content-Type", contentType)
+		} else {
+			req.Header.Set("Content-Type", jsonContentType)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if attempt >= c.maxAttempts || !isTransient(method, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		backoff := c.initialBackoff
+		for i := 1; i < attempt && backoff < maxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		c.log().Println("attempt", attempt, "of", c.maxAttempts, "failed, retrying in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isTransient reports whether a request failed in a way that may succeed when retried.
+// POST is never retried, since TeamCity may have acted on the first request, e.g. queued a build.
+func isTransient(method string, resp *http.Response, err error) bool {
+	if method == "POST" {
+		return false
+	}
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// buildLogURL returns the URL of the log of the build with specified id, which is served outside of the REST API
+func (c *Client) buildLogURL(buildId int) string {
+	return c.host + fmt.Sprintf("/downloadBuildLog.html?buildId=%d", buildId)
+}
+
+// log returns the logger of the client, falling back to the package level Logger
+func (c *Client) log() *log.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return Logger
+}
+
+// basePath returns the REST API root, which token authentication accesses without the httpAuth prefix
+// and guest access through the guestAuth prefix
+func (c *Client) basePath() string {
+	if c.guest {
+		return guestPathPrefix
+	}
+	if len(c.token) > 0 {
+		return restPathPrefix
+	}
+	return basePathSuffix
+}
+
+func (c *Client) setAuthorization(req *http.Request) {
+	if c.guest {
+		return
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return
+	}
+	rawAuth := []byte(fmt.Sprintf("%v:%v", c.username, c.password))
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString(rawAuth))
+}
@@ -0,0 +1,33 @@
+package teamcity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenClientListProjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer secret-token"; got != want {
+			t.Errorf("got Authorization %q, want %q", got, want)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got, want := r.URL.Path, "/app/rest/projects"; got != want {
+			t.Errorf("got path %q, want %q", got, want)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"count":1,"project":[{"id":"_Root","name":"<Root project>"}]}`))
+	}))
+	defer server.Close()
+
+	projects, err := NewClientWithToken(server.URL, "secret-token").ListProjects()
+	if err != nil {
+		t.Fatalf("ListProjects: %v", err)
+	}
+	if len(projects.Projects) != 1 || projects.Projects[0].Id != "_Root" {
+		t.Errorf("got projects %+v, want only _Root", projects.Projects)
+	}
+}
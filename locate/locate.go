@@ -1,6 +1,36 @@
+// Package locate builds TeamCity locators, the key:value expressions the REST API uses to select entities.
+// Combine several with New, or pass a preformatted string as Raw for dimensions without a constructor.
+//
+// Entities by identity: ById, ByName, ByNumber, ByVersion.
+//
+// Builds by build type or project: ByBuildType, ByBuildTypeID, ByProject, ByProjectID, ByProjectName,
+// ByAffectedProject.
+//
+// Builds by branch: ByBranch, ByBranchSelector.
+//
+// Builds by outcome and state: ByStatus, ByState, ByCanceled, ByPersonal, ByPinned, ByRunning, ByQueued,
+// ByDefaultFilter.
+//
+// Build chains: BySnapshotDependency, ByTo, ByIncludeInitial.
+//
+// Build types by agent: ByCompatibleAgent.
+//
+// Test occurrences: ByTestName, ByTestNameContains.
+//
+// Paging: ByCount, ByLookupLimit.
 package locate
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// Branch selectors for use with ByBranchSelector
+const (
+	BranchSelectorAll     = "ALL_BRANCHES"
+	BranchSelectorDefault = "DEFAULT"
+	BranchSelectorActive  = "ACTIVE_HISTORY_AND_ACTIVE_VCS_BRANCHES"
+)
 
 // Locator is a key, value used to locate various TeamCity entities
 type Locator struct {
@@ -13,11 +43,45 @@ func (l Locator) String() string {
 	return l.key + ":" + l.value
 }
 
+// Raw is a locator given as a preformatted string, for dimensions without a dedicated constructor
+type Raw string
+
+// String returns the raw locator string
+func (r Raw) String() string {
+	return string(r)
+}
+
+// CompositeLocator combines several locators to locate by all of their dimensions at once,
+// e.g. buildType:(id:Foo),branch:master,status:SUCCESS
+type CompositeLocator struct {
+	locators []Locator
+}
+
+// New creates a CompositeLocator from the given locators
+func New(locators ...Locator) CompositeLocator {
+	return CompositeLocator{locators: locators}
+}
+
+// String converts the locator to a string in the form key1:value1,key2:value2
+func (c CompositeLocator) String() string {
+	parts := make([]string, len(c.locators))
+	for i, l := range c.locators {
+		parts[i] = l.String()
+	}
+	return strings.Join(parts, ",")
+}
+
 // ById gets the Locator for locating by id
 func ById(id string) Locator {
 	return Locator{"id", id}
 }
 
+// ByNumber gets the Locator for locating a build by its displayed build number, e.g. "1.2.3.456".
+// Unlike ById, which matches TeamCity's internal integer id, build numbers are only unique within a build type.
+func ByNumber(n string) Locator {
+	return Locator{"number", n}
+}
+
 // ByName gets the Locator for locating by name
 func ByName(name string) Locator {
 	return Locator{"name", name}
@@ -33,6 +97,11 @@ func ByBuildType(l Locator) Locator {
 	return Locator{"buildType", fmt.Sprintf("(%v)", l.String())}
 }
 
+// ByBuildTypeID gets the Locator for locating by the id of a build type, short for ByBuildType(ById(id))
+func ByBuildTypeID(id string) Locator {
+	return ByBuildType(ById(id))
+}
+
 // ByAffectedProject gets the Locator for locating by affected project locator
 func ByAffectedProject(l Locator) Locator {
 	return Locator{"affectedProject", fmt.Sprintf("(%v)", l.String())}
@@ -43,6 +112,16 @@ func ByProject(l Locator) Locator {
 	return Locator{"project", fmt.Sprintf("(%v)", l.String())}
 }
 
+// ByProjectID gets the Locator for locating by the id of a project, short for ByProject(ById(id))
+func ByProjectID(id string) Locator {
+	return ByProject(ById(id))
+}
+
+// ByProjectName gets the Locator for locating by the name of a project, short for ByProject(ByName(name))
+func ByProjectName(name string) Locator {
+	return ByProject(ByName(name))
+}
+
 // BySnapshotDependency gets the Locator for locating by to locator
 func BySnapshotDependency(locators ...Locator) Locator {
 	var v string
@@ -61,3 +140,82 @@ func ByIncludeInitial(b bool) Locator {
 func ByTo(l Locator) Locator {
 	return Locator{"to", fmt.Sprintf("(%v)", l.String())}
 }
+
+// ByCompatibleAgent gets the Locator for locating build types that can run on the agent
+// with the given agent locator, e.g. ById("12") or ByName("agentName")
+func ByCompatibleAgent(agentLocator Locator) Locator {
+	return Locator{"compatibleAgent", fmt.Sprintf("(%v)", agentLocator.String())}
+}
+
+// ByCanceled gets the Locator for locating builds by whether they were canceled
+func ByCanceled(b bool) Locator {
+	return Locator{"canceled", fmt.Sprintf("%v", b)}
+}
+
+// ByTestName gets the Locator for locating test occurrences by their exact test name, e.g. with SelectTestOccurrences
+func ByTestName(name string) Locator {
+	return Locator{"name", name}
+}
+
+// ByTestNameContains gets the Locator for locating test occurrences whose test name contains substring
+func ByTestNameContains(substring string) Locator {
+	return Locator{"name", fmt.Sprintf("(value:%v,matchType:contains)", substring)}
+}
+
+// ByBranchSelector gets the Locator for locating by a branch selector, one of the BranchSelector constants
+func ByBranchSelector(selector string) Locator {
+	return Locator{"branchSelector", selector}
+}
+
+// ByBranch gets the Locator for locating builds by branch name, e.g. "master" or "refs/heads/feature"
+func ByBranch(branch string) Locator {
+	return Locator{"branch", fmt.Sprintf("(name:%v)", branch)}
+}
+
+// ByStatus gets the Locator for locating builds by status, e.g. "SUCCESS", "FAILURE" or "UNKNOWN"
+func ByStatus(status string) Locator {
+	return Locator{"status", status}
+}
+
+// ByState gets the Locator for locating builds by state, e.g. "queued", "running" or "finished"
+func ByState(state string) Locator {
+	return Locator{"state", state}
+}
+
+// ByCount gets the Locator for limiting the number of entities returned
+func ByCount(n int) Locator {
+	return Locator{"count", fmt.Sprintf("%d", n)}
+}
+
+// ByLookupLimit gets the Locator for limiting the number of entities TeamCity examines while locating,
+// which bounds the cost of searching long build histories
+func ByLookupLimit(n int) Locator {
+	return Locator{"lookupLimit", fmt.Sprintf("%d", n)}
+}
+
+// ByPersonal gets the Locator for locating builds by whether they are personal builds
+func ByPersonal(b bool) Locator {
+	return Locator{"personal", fmt.Sprintf("%v", b)}
+}
+
+// ByPinned gets the Locator for locating builds by whether they are pinned
+func ByPinned(b bool) Locator {
+	return Locator{"pinned", fmt.Sprintf("%v", b)}
+}
+
+// ByRunning gets the Locator for locating builds by whether they are running
+func ByRunning(b bool) Locator {
+	return Locator{"running", fmt.Sprintf("%v", b)}
+}
+
+// ByQueued gets the Locator for locating builds by whether they are still queued.
+// TeamCity has no queued dimension, so this is expressed with the state dimension.
+func ByQueued(b bool) Locator {
+	return Locator{"state", fmt.Sprintf("(queued:%v,running:%v,finished:%v)", b, !b, !b)}
+}
+
+// ByDefaultFilter gets the Locator for turning TeamCity's default build filter on or off. The default filter
+// hides canceled, failed to start, running, personal and non-default branch builds.
+func ByDefaultFilter(b bool) Locator {
+	return Locator{"defaultFilter", fmt.Sprintf("%v", b)}
+}
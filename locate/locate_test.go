@@ -0,0 +1,13 @@
+package locate
+
+import "testing"
+
+func TestByBuildTypeID(t *testing.T) {
+	got := ByBuildTypeID("MyProject_Build").String()
+	if want := "buildType:(id:MyProject_Build)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if alias := ByBuildType(ById("MyProject_Build")).String(); got != alias {
+		t.Errorf("got %q, want the same as ByBuildType(ById(...)) %q", got, alias)
+	}
+}
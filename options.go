@@ -0,0 +1,92 @@
+package teamcity
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ClientOption configures a Client created with NewClientWithOptions
+type ClientOption func(*Client)
+
+// NewClientWithOptions creates a new Client for host configured by the given options
+func NewClientWithOptions(host string, opts ...ClientOption) *Client {
+	c := NewClient(host, "", "")
+	for _, opt := range opts {
+		opt(c)
+	}
+	// the timeout is applied last so it holds regardless of where WithHTTPClient appears
+	if c.timeout > 0 {
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
+}
+
+// WithCredentials authenticates requests with the specified username and password
+func WithCredentials(username, password string) ClientOption {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithRetry retries requests that fail with a transient error up to maxAttempts times in total,
+// waiting initialBackoff before the first retry and doubling the wait on every following one.
+// Only idempotent requests are retried: GET, HEAD, PUT and DELETE. POST is never retried, so
+// creating builds or entities cannot produce duplicates.
+func WithRetry(maxAttempts int, initialBackoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.initialBackoff = initialBackoff
+	}
+}
+
+// WithHTTPClient sends requests with the specified *http.Client instead of http.DefaultClient,
+// e.g. to configure timeouts, proxies or TLS settings
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with the specified access token instead of a username and password
+func WithToken(token string) ClientOption {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithGuestAuth sends requests without credentials through TeamCity's guest access path, which has to be
+// enabled on the server. ArtifactURL then returns guest URLs that can be fetched without credentials.
+func WithGuestAuth() ClientOption {
+	return func(c *Client) {
+		c.guest = true
+	}
+}
+
+// WithTimeout limits the time each request may take, including reading the response body.
+// It applies to the *http.Client given with WithHTTPClient too, which is copied rather than modified,
+// since it may be shared, e.g. http.DefaultClient.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithLogger logs the requests and responses of the client to l instead of the package level Logger
+func WithLogger(l *log.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithBaseURL sends requests to the server at baseURL instead of the host given to NewClientWithOptions,
+// e.g. https://ci.example.com/teamcity for a server that is not at the root of its host
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.host = strings.TrimSuffix(baseURL, "/")
+	}
+}
@@ -9,14 +9,42 @@ type Project struct {
 	ParentProjectId string   `json:"parentProjectId,omitempty"`
 	ParentProject   *Project `json:"parentProject,omitempty"`
 	Archived        bool     `json:"archived,omitempty"`
+	// Locator identifies an existing project when a Project is sent as a reference to it
+	Locator string `json:"locator,omitempty"`
 }
 
 // Projects is a list of TeamCity projects and aggregate details
 type Projects struct {
+	Count    int       `json:"count,omitempty"`
+	Href     string    `json:"href,omitempty"`
+	NextHref string    `json:"nextHref,omitempty"`
 	Projects []Project `json:"project,omitempty"`
 }
 
+// PaginatedProjects is a page of TeamCity projects along with the link to the next page
+type PaginatedProjects = Projects
+
 // PropertyFromName returns the Property of the given Project with the given target name if it exists
 func (project Project) PropertyFromName(target string) Property {
 	return project.Params.PropertyFromName(target)
 }
+
+// FindByID returns the project with the given id, or nil if there is none
+func (ps *Projects) FindByID(id string) *Project {
+	for i := range ps.Projects {
+		if ps.Projects[i].Id == id {
+			return &ps.Projects[i]
+		}
+	}
+	return nil
+}
+
+// FindByName returns the first project with the given name, or nil if there is none
+func (ps *Projects) FindByName(name string) *Project {
+	for i := range ps.Projects {
+		if ps.Projects[i].Name == name {
+			return &ps.Projects[i]
+		}
+	}
+	return nil
+}
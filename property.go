@@ -1,10 +1,21 @@
 package teamcity
 
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // Property is a characteristic of a project or build configuration
 type Property struct {
-	Name  string `json:"name,omitempty"`
-	Value string `json:"value"`
-	Own   bool   `json:"own,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Value     string         `json:"value"`
+	Own       bool           `json:"own,omitempty"`
+	Inherited bool           `json:"inherited,omitempty"`
+	Spec      *ParameterSpec `json:"type,omitempty"`
 }
 
 // Params is a container for the various properties of a project or build configuration
@@ -21,3 +32,106 @@ func (params Params) PropertyFromName(target string) Property {
 	}
 	return Property{}
 }
+
+// ParameterSpec describes how a parameter is presented and validated, e.g. as a password or a select list.
+// Attributes without a typed field, e.g. display='hidden' or checkedValue='y', are preserved as they were read.
+type ParameterSpec struct {
+	Type        string // one of "text", "password", "checkbox" or "select"
+	Label       string
+	Description string
+	Options     []string
+
+	// raw is the specification as read from TeamCity, written back unchanged unless the typed fields
+	// differ from read, which holds their values at the time
+	raw  string
+	read *ParameterSpec
+	// extra holds the attributes without a typed field, still escaped
+	extra []string
+}
+
+type jsonParameterSpec struct {
+	RawValue string `json:"rawValue"`
+}
+
+var specAttribute = regexp.MustCompile(`(\w+)='((?:[^'|]|\|.)*)'`)
+
+var specEscaper = strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]")
+var specUnescaper = strings.NewReplacer("||", "|", "|'", "'", "|n", "\n", "|r", "\r", "|[", "[", "|]", "]")
+
+// UnmarshalJSON parses the raw specification TeamCity provides, e.g. select label='Env' data_1='qa' data_2='prod'
+func (s *ParameterSpec) UnmarshalJSON(data []byte) error {
+	var js jsonParameterSpec
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	*s = ParameterSpec{raw: js.RawValue}
+	if fields := strings.Fields(js.RawValue); len(fields) > 0 {
+		s.Type = fields[0]
+	}
+	options := map[int]string{}
+	for _, m := range specAttribute.FindAllStringSubmatch(js.RawValue, -1) {
+		value := specUnescaper.Replace(m[2])
+		switch {
+		case m[1] == "label":
+			s.Label = value
+		case m[1] == "description":
+			s.Description = value
+		case strings.HasPrefix(m[1], "data_"):
+			if i, err := strconv.Atoi(strings.TrimPrefix(m[1], "data_")); err == nil {
+				options[i] = value
+				continue
+			}
+			s.extra = append(s.extra, m[0])
+		default:
+			s.extra = append(s.extra, m[0])
+		}
+	}
+	var keys []int
+	for i := range options {
+		keys = append(keys, i)
+	}
+	sort.Ints(keys)
+	for _, i := range keys {
+		s.Options = append(s.Options, options[i])
+	}
+	s.read = &ParameterSpec{Type: s.Type, Label: s.Label, Description: s.Description, Options: s.Options}
+	return nil
+}
+
+// modified reports whether the typed fields were changed since the specification was read
+func (s ParameterSpec) modified() bool {
+	if s.read == nil {
+		return true
+	}
+	if s.Type != s.read.Type || s.Label != s.read.Label || s.Description != s.read.Description ||
+		len(s.Options) != len(s.read.Options) {
+		return true
+	}
+	for i := range s.Options {
+		if s.Options[i] != s.read.Options[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON formats the specification the way TeamCity expects it
+func (s ParameterSpec) MarshalJSON() ([]byte, error) {
+	if !s.modified() {
+		return json.Marshal(jsonParameterSpec{RawValue: s.raw})
+	}
+	raw := s.Type
+	if len(s.Label) > 0 {
+		raw += fmt.Sprintf(" label='%v'", specEscaper.Replace(s.Label))
+	}
+	if len(s.Description) > 0 {
+		raw += fmt.Sprintf(" description='%v'", specEscaper.Replace(s.Description))
+	}
+	for i, option := range s.Options {
+		raw += fmt.Sprintf(" data_%d='%v'", i+1, specEscaper.Replace(option))
+	}
+	for _, attr := range s.extra {
+		raw += " " + attr
+	}
+	return json.Marshal(jsonParameterSpec{RawValue: raw})
+}
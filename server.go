@@ -0,0 +1,10 @@
+package teamcity
+
+// LicensingData describes the license limits of the TeamCity server and how much of them is left
+type LicensingData struct {
+	MaxAgents         int    `json:"maxAgents,omitempty"`
+	AgentsLeft        int    `json:"agentsLeft,omitempty"`
+	MaxBuildTypes     int    `json:"maxBuildTypes,omitempty"`
+	BuildTypesLeft    int    `json:"buildTypesLeft,omitempty"`
+	ServerLicenseType string `json:"serverLicenseType,omitempty"`
+}
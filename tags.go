@@ -0,0 +1,56 @@
+package teamcity
+
+// Tag is a label attached to a build
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Tags is the list of tags of a build
+type Tags struct {
+	Count int   `json:"count,omitempty"`
+	Tags  []Tag `json:"tag,omitempty"`
+}
+
+// Names returns the name of each tag
+func (t *Tags) Names() []string {
+	if t == nil {
+		return nil
+	}
+	names := make([]string, len(t.Tags))
+	for i, tag := range t.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// NewTags creates Tags with the given names
+func NewTags(t []string) *Tags {
+	tags := []Tag{}
+	for _, tag := range t {
+		tags = append(tags, Tag{Name: tag})
+	}
+	return &Tags{Count: len(tags), Tags: tags}
+}
+
+// Add adds a tag with the given name, unless one already exists
+func (t *Tags) Add(name string) {
+	for _, tag := range t.Tags {
+		if tag.Name == name {
+			return
+		}
+	}
+	t.Tags = append(t.Tags, Tag{Name: name})
+	t.Count = len(t.Tags)
+}
+
+// Remove removes the tag with the given name, reporting whether it existed
+func (t *Tags) Remove(name string) bool {
+	for i, tag := range t.Tags {
+		if tag.Name == name {
+			t.Tags = append(t.Tags[:i], t.Tags[i+1:]...)
+			t.Count = len(t.Tags)
+			return true
+		}
+	}
+	return false
+}
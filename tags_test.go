@@ -0,0 +1,47 @@
+package teamcity
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTagsJSONRoundTrip(t *testing.T) {
+	data := `{"count":2,"tag":[{"name":"release"},{"name":"hotfix"}]}`
+
+	var tags Tags
+	if err := json.Unmarshal([]byte(data), &tags); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if want := []string{"release", "hotfix"}; tags.Count != 2 || !reflect.DeepEqual(tags.Names(), want) {
+		t.Fatalf("got count %d and names %v, want 2 and %v", tags.Count, tags.Names(), want)
+	}
+
+	b, err := json.Marshal(tags)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(b) != data {
+		t.Errorf("got %s, want %s", b, data)
+	}
+}
+
+func TestTagsAddRemove(t *testing.T) {
+	tags := NewTags([]string{"release"})
+
+	tags.Add("hotfix")
+	tags.Add("release")
+	if want := []string{"release", "hotfix"}; tags.Count != 2 || !reflect.DeepEqual(tags.Names(), want) {
+		t.Fatalf("after Add got count %d and names %v, want 2 and %v", tags.Count, tags.Names(), want)
+	}
+
+	if !tags.Remove("release") {
+		t.Error("Remove(release) = false, want true")
+	}
+	if tags.Remove("missing") {
+		t.Error("Remove(missing) = true, want false")
+	}
+	if want := []string{"hotfix"}; tags.Count != 1 || !reflect.DeepEqual(tags.Names(), want) {
+		t.Errorf("after Remove got count %d and names %v, want 1 and %v", tags.Count, tags.Names(), want)
+	}
+}
@@ -0,0 +1,182 @@
+package teamcity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ProjectReader reads projects and their configuration
+type ProjectReader interface {
+	ListProjects() (*Projects, error)
+	ListProjectsContext(ctx context.Context) (*Projects, error)
+	ListProjectsPage(href string) (*PaginatedProjects, error)
+	ListAllProjects() (*Projects, error)
+	SelectProject(selector string) (*Project, error)
+	SelectProjects(selector string) (*Projects, error)
+	IterateProjects(ctx context.Context, pageSize int) (<-chan Project, <-chan error)
+	GetSubProjectIDs(parentLocator string) ([]string, error)
+	GetProjectRoleAssignments(projectLocator string) ([]RoleAssignment, error)
+	CanEditProject(projectLocator string) (bool, error)
+	GetProjectParameters(projectLocator string) (*Params, error)
+	GetProjectBuildStatus(projectLocator string) (string, error)
+}
+
+// ProjectWriter creates and modifies projects
+type ProjectWriter interface {
+	CreateProject(project *Project) (*Project, error)
+	ArchiveProject(projectLocator string) error
+	UnarchiveProject(projectLocator string) error
+	SetParentProject(projectLocator string, newParentLocator string) (*Project, error)
+	UpdateParameter(projectLocator string, property *Property) (*Property, error)
+	DeleteProjectParameter(projectLocator, paramName string) error
+	ReplaceProjectParameters(projectLocator string, params *Params) (*Params, error)
+}
+
+// BuildReader reads builds and their details
+type BuildReader interface {
+	SelectBuilds(selector fmt.Stringer) (*Builds, error)
+	SelectBuildsContext(ctx context.Context, selector fmt.Stringer) (*Builds, error)
+	SelectBuildsWithFields(selector fmt.Stringer, fields string) (*Builds, error)
+	SelectBuildsPage(href string) (*Builds, error)
+	SelectAllBuilds(selector fmt.Stringer) (*Builds, error)
+	GetBuildChainStatuses(buildLocator string) (map[string]string, error)
+	GetDownstreamBuilds(buildLocator string) (*Builds, error)
+	HasRunningBuilds(selector string) (bool, error)
+	GetLatestBuild(buildTypeLocator string) (*Build, error)
+	BuildFromID(id int) (*Build, error)
+	BuildFromIDContext(ctx context.Context, id int) (*Build, error)
+	WaitForBuild(ctx context.Context, buildID int, pollInterval time.Duration) (*Build, error)
+	WaitForBuildWithCallback(ctx context.Context, buildID int, pollInterval time.Duration, progress func(*Build)) (*Build, error)
+	GetBuildVcsLabel(buildID int) (string, error)
+	IsBuildPinned(buildLocator string) (bool, error)
+	GetBuildAgentPool(buildID int) (*AgentPool, error)
+	GetBuildCause(buildID int) (*BuildCause, error)
+	GetBuildRevisionByVcsRoot(buildID int, vcsRootLocator string) (string, error)
+	GetBuildStopMessage(buildID int) (string, error)
+	GetBuildEstimatedStartTime(buildID int) (*time.Time, error)
+	SelectChange(selector string) (*Change, error)
+	GetChangeWithStats(changeLocator string) (*Change, error)
+	SelectBuildStats(selector string) (*PropertyList, error)
+	GetBuildStatistics(buildID int) (BuildStatistics, error)
+	GetTestOccurrences(buildID int) ([]TestOccurrence, error)
+	SelectTestOccurrences(buildID int, selector fmt.Stringer) ([]TestOccurrence, error)
+	GetTagByLocator(locator string) (*Tags, error)
+	GetBuildTags(buildID int) (*Tags, error)
+	DownloadBuildLog(buildId int, w io.WriteCloser) error
+	GetBuildLog(buildLocator string) (io.ReadCloser, error)
+	GetBuildLogContext(ctx context.Context, buildID int) (io.ReadCloser, error)
+	GetBuildLogText(ctx context.Context, buildID int) (string, error)
+	ArtifactURL(buildLocator, artifactPath string) string
+	DownloadArtifact(buildLocator, artifactPath string) (io.ReadCloser, error)
+	ListArtifacts(buildLocator string) (*Artifacts, error)
+	ListArtifactChildren(buildLocator, artifactPath string) (*Artifacts, error)
+	ListArtifactsByPattern(buildID int, basePath, pattern string) (*Artifacts, error)
+	ListBuildArtifacts(buildID int, artifactPath string) (*Artifacts, error)
+	DownloadBuildArtifact(buildID int, artifactPath string) (io.ReadCloser, error)
+	StreamArtifactsZip(buildID int, basePath string, w io.Writer) error
+	GetArtifactHash(buildID int, artifactPath string) (string, error)
+	GetBuildDownloadableDependencies(buildID int) ([]ArtifactDownload, error)
+}
+
+// BuildWriter triggers and modifies builds
+type BuildWriter interface {
+	TriggerBuildID(buildTypeId string, changeId int, pushDescription string) (*Build, error)
+	TriggerBuildIDWithProperties(buildTypeId string, changeId int, pushDescription string, props map[string]string) (*Build, error)
+	TriggerBuild(build *Build, pushDescription string) (*Build, error)
+	TriggerBuildContext(ctx context.Context, build *Build, pushDescription string) (*Build, error)
+	TriggerBuildIfAbsent(build *Build, dedupeKey string) (*Build, bool, error)
+	CancelBuild(buildID int, comment string, reAddToQueue bool) error
+	PinBuild(buildLocator, comment string) error
+	UnpinBuild(buildLocator string) error
+	PinBuildID(buildID int, comment string) error
+	UnpinBuildID(buildID int) error
+	SetTagByLocator(locator string, tags *Tags) (*Tags, error)
+	SetBuildTags(buildID int, tags *Tags) (*Tags, error)
+	AddBuildTag(buildID int, tag string) (*Tags, error)
+	UpdateTags(buildLocator string, fn func(current []string) []string) error
+}
+
+// BuildTypeReader reads build configurations and their settings
+type BuildTypeReader interface {
+	SelectBuildType(selector string) (*BuildType, error)
+	GetBuildTypeProjectID(buildTypeLocator string) (string, error)
+	GetBuildTypeProject(buildTypeLocator string) (*Project, error)
+	SelectBuildTypes(selector fmt.Stringer) (*BuildTypes, error)
+	SelectBuildTypesWithFields(selector fmt.Stringer, fields string) (*BuildTypes, error)
+	SelectBuildTypeBuilds(selector fmt.Stringer) (*Builds, error)
+	GetProjectBuildTypes(projectLocator string, ownOnly bool) (*BuildTypes, error)
+	GetBuildTypeIDs(projectLocator string) ([]string, error)
+	SelectSnapshotDependency(buildTypeSelector string, dependencyId string) (*Dependency, error)
+	SelectArtifactDependencies(buildTypeSelector string) (*ArtifactDependencies, error)
+	SelectSnapshotDependencies(buildTypeSelector string) (*SnapshotDependencies, error)
+	SelectTriggers(buildTypeSelector string) (*Triggers, error)
+	GetTriggerBranchFilter(buildTypeLocator, triggerId string) (string, error)
+	GetBuildTypeFeatureCount(buildTypeLocator string) (int, error)
+	GetBuildNumberFormat(buildTypeLocator string) (string, error)
+	GetBuildTypePaused(buildTypeSelector string) (bool, error)
+	GetBuildTypeRevisions(buildTypeLocator string) (map[string]string, error)
+	ExportBuildType(buildTypeLocator string) (*BuildTypeExport, error)
+	GetInheritedBuildSteps(buildTypeLocator string) (*BuildSteps, error)
+	GetBuildStepRunnerType(buildTypeLocator, stepID string) (string, error)
+	GetBuildTypeParameters(buildTypeLocator string) (*Params, error)
+	ResolveParameterAcrossBuildTypes(name string, buildTypeLocators []string) (map[string]string, error)
+}
+
+// BuildTypeWriter creates and modifies build configurations
+type BuildTypeWriter interface {
+	CreateBuildType(projectLocator string, buildType *BuildType) (*BuildType, error)
+	UpdateBuildTypeParameter(buildTypeLocator string, property *Property) (*Property, error)
+	ReplaceBuildTypeParameters(buildTypeLocator string, params *Params) (*Params, error)
+	DeleteSnapshotDependency(buildTypeSelector string, dependency *Dependency) error
+	DeleteSnapshotDependencyID(buildTypeSelector, dependencyId string) error
+	CreateSnapshotDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error)
+	SetSnapshotDependencies(buildTypeLocator string, sourceBuildTypeIds []string) error
+	ImportBuildType(projectLocator string, export *BuildTypeExport) (*BuildType, error)
+	CreateArtifactDependency(buildTypeSelector string, dependency *Dependency) (*Dependency, error)
+	DeleteArtifactDependency(buildTypeSelector, dependencyId string) error
+	CreateTrigger(buildTypeSelector string, trigger *Trigger) (*Trigger, error)
+	DeleteTrigger(buildTypeSelector, triggerId string) error
+	SetTriggerBranchFilter(buildTypeLocator, triggerId, filter string) error
+	ApplyTemplate(buildTypeSelector string, templateSelector string) (*BuildType, error)
+	SetBuildNumberFormat(buildTypeLocator string, format string) (string, error)
+	SetBuildTypePaused(buildTypeSelector string, paused bool) error
+}
+
+// VcsRootReader reads version control system roots
+type VcsRootReader interface {
+	SelectVcsRoot(selector string) (*VcsRoot, error)
+	ListVcsRoots() (*VcsRoots, error)
+	GetVcsRootByURL(repoURL string) (*VcsRoot, error)
+}
+
+// VcsRootWriter creates and modifies version control system roots
+type VcsRootWriter interface {
+	CreateVcsRoot(root *VcsRoot) (*VcsRoot, error)
+	UpdateVcsRoot(selector string, vcsRoot *VcsRoot) (*VcsRoot, error)
+	SetVcsRootProperty(selector, name, value string) error
+	DeleteVcsRoot(selector string) error
+}
+
+// ServerReader reads information about the TeamCity server itself
+type ServerReader interface {
+	GetLicensingData() (*LicensingData, error)
+	GetServerPublicKey() ([]byte, error)
+}
+
+// TeamCity is the full set of operations provided by Client.
+// Depend on it, or on one of the smaller interfaces it is made of, to substitute a fake in tests.
+type TeamCity interface {
+	ProjectReader
+	ProjectWriter
+	BuildReader
+	BuildWriter
+	BuildTypeReader
+	BuildTypeWriter
+	VcsRootReader
+	VcsRootWriter
+	ServerReader
+}
+
+var _ TeamCity = (*Client)(nil)
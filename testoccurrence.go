@@ -0,0 +1,20 @@
+package teamcity
+
+// TestOccurrence is the result of running a single test in a build
+type TestOccurrence struct {
+	Id     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status,omitempty"`
+	// Duration is how long the test ran, in milliseconds
+	Duration    int    `json:"duration,omitempty"`
+	Details     string `json:"details,omitempty"`
+	IgnoredInfo string `json:"ignoreDetails,omitempty"`
+}
+
+// TestOccurrences is a page of test results
+type TestOccurrences struct {
+	Count           int              `json:"count,omitempty"`
+	Href            string           `json:"href,omitempty"`
+	NextHref        string           `json:"nextHref,omitempty"`
+	TestOccurrences []TestOccurrence `json:"testOccurrence,omitempty"`
+}
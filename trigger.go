@@ -2,24 +2,81 @@ package teamcity
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 )
 
+// Types of triggers
+const (
+	TriggerTypeDependency = "buildDependencyTrigger"
+	TriggerTypeVcs        = "vcsTrigger"
+	TriggerTypeSchedule   = "schedulingTrigger"
+)
+
+// cronProperties are the schedule trigger properties holding each field of a cron expression, in order
+var cronProperties = []string{
+	"cronExpression_sec",
+	"cronExpression_min",
+	"cronExpression_hour",
+	"cronExpression_dm",
+	"cronExpression_month",
+	"cronExpression_dw",
+	"cronExpression_year",
+}
+
 // Trigger represents something that kicks off a build type.
+// Type defaults to TriggerTypeDependency, which is configured by DependsOn and AfterSuccessfulBuildOnly.
+// Other types are configured by Properties, e.g. branchFilter and triggerRules for TriggerTypeVcs.
 type Trigger struct {
 	Id                       string
+	Type                     string
 	DependsOn                string
 	AfterSuccessfulBuildOnly bool
+	Properties               map[string]string
+	// Inherited is set for triggers that come from the template of the build type
+	Inherited bool
+	Disabled  bool
 }
 
 type jsonTrigger struct {
 	Id           string        `json:"id,omitempty"`
 	Type         string        `json:"type,omitempty"`
 	PropertyList *PropertyList `json:"properties,omitempty"`
+	Inherited    bool          `json:"inherited,omitempty"`
+	Disabled     bool          `json:"disabled,omitempty"`
 }
 
+// Triggers is a list of Trigger
 type Triggers struct {
-	Triggers []jsonTrigger `json:"trigger,omitempty"`
+	Triggers []Trigger `json:"trigger,omitempty"`
+}
+
+// NewVcsTrigger creates a trigger that runs the build type when changes matching triggerRules are
+// pushed to branches matching branchFilter. Either may be empty to match everything.
+func NewVcsTrigger(branchFilter, triggerRules string) *Trigger {
+	props := map[string]string{}
+	if len(branchFilter) > 0 {
+		props[branchFilterProperty] = branchFilter
+	}
+	if len(triggerRules) > 0 {
+		props["triggerRules"] = triggerRules
+	}
+	return &Trigger{Type: TriggerTypeVcs, Properties: props}
+}
+
+// NewScheduleTrigger creates a trigger that runs the build type on a schedule given as a cron expression
+// with seconds, minutes, hours, day of month, month, day of week and an optional year, e.g. "0 0 2 * * ?"
+func NewScheduleTrigger(cronExpression string) (*Trigger, error) {
+	fields := strings.Fields(cronExpression)
+	if len(fields) != len(cronProperties) && len(fields) != len(cronProperties)-1 {
+		return nil, fmt.Errorf("teamcity: invalid cron expression %q: expected %d or %d fields", cronExpression, len(cronProperties)-1, len(cronProperties))
+	}
+	props := map[string]string{"schedulingPolicy": "cron"}
+	for i, field := range fields {
+		props[cronProperties[i]] = field
+	}
+	return &Trigger{Type: TriggerTypeSchedule, Properties: props}, nil
 }
 
 func (t *Trigger) UnmarshalJSON(data []byte) error {
@@ -29,20 +86,42 @@ func (t *Trigger) UnmarshalJSON(data []byte) error {
 		return e
 	}
 	*t = Trigger{
-		Id:                       jt.Id,
-		DependsOn:                jt.PropertyList.Value("dependsOn"),
-		AfterSuccessfulBuildOnly: jt.PropertyList.Bool("afterSuccessfulBuildOnly"),
+		Id:        jt.Id,
+		Type:      jt.Type,
+		Inherited: jt.Inherited,
+		Disabled:  jt.Disabled,
+	}
+	if jt.PropertyList != nil {
+		t.Properties = map[string]string{}
+		for _, p := range jt.PropertyList.Properties {
+			t.Properties[p.Name] = p.Value
+		}
+	}
+	if jt.Type == TriggerTypeDependency {
+		t.DependsOn = jt.PropertyList.Value("dependsOn")
+		t.AfterSuccessfulBuildOnly = jt.PropertyList.Bool("afterSuccessfulBuildOnly")
 	}
 	return nil
 }
 
 func (t Trigger) MarshalJSON() ([]byte, error) {
+	triggerType := t.Type
+	if len(triggerType) == 0 {
+		triggerType = TriggerTypeDependency
+	}
+	props := map[string]string{}
+	for k, v := range t.Properties {
+		props[k] = v
+	}
+	if triggerType == TriggerTypeDependency {
+		props["dependsOn"] = t.DependsOn
+		props["afterSuccessfulBuildOnly"] = strconv.FormatBool(t.AfterSuccessfulBuildOnly)
+	}
 	return json.Marshal(jsonTrigger{
-		Id:   t.Id,
-		Type: "buildDependencyTrigger",
-		PropertyList: NewPropertyList(map[string]string{
-			"dependsOn":                t.DependsOn,
-			"afterSuccessfulBuildOnly": strconv.FormatBool(t.AfterSuccessfulBuildOnly),
-		}),
+		Id:           t.Id,
+		Type:         triggerType,
+		PropertyList: NewPropertyList(props),
+		Inherited:    t.Inherited,
+		Disabled:     t.Disabled,
 	})
 }
@@ -0,0 +1,72 @@
+package teamcity
+
+// User describes a user on TeamCity
+type User struct {
+	Id       int     `json:"id,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Roles    *Roles  `json:"roles,omitempty"`
+	Groups   *Groups `json:"groups,omitempty"`
+}
+
+// Users is a list of TeamCity users
+type Users struct {
+	Users []User `json:"user,omitempty"`
+}
+
+// Group describes a user group on TeamCity
+type Group struct {
+	Key   string `json:"key,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Roles *Roles `json:"roles,omitempty"`
+}
+
+// Groups is a list of TeamCity user groups
+type Groups struct {
+	Groups []Group `json:"group,omitempty"`
+}
+
+// Role is a role granted to a user or group within a scope.
+// Scope is "g" for global roles or "p:<projectId>" for project roles.
+type Role struct {
+	RoleId string `json:"roleId,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+}
+
+// Roles is a container for a list of Role's
+type Roles struct {
+	Roles []Role `json:"role,omitempty"`
+}
+
+// RoleAssignment is a role held by either a user or a group that is effective on a project
+type RoleAssignment struct {
+	User  *User
+	Group *Group
+	Role  Role
+}
+
+const globalRoleScope = "g"
+
+// projectEditRoles are the built-in roles that allow editing project settings
+var projectEditRoles = map[string]bool{
+	"SYSTEM_ADMIN":  true,
+	"PROJECT_ADMIN": true,
+}
+
+func projectRoleScope(projectId string) string {
+	return "p:" + projectId
+}
+
+// effectiveIn returns the roles whose scope is one of the given scopes
+func (r *Roles) effectiveIn(scopes map[string]bool) []Role {
+	if r == nil {
+		return nil
+	}
+	var roles []Role
+	for _, role := range r.Roles {
+		if scopes[role.Scope] {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
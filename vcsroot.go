@@ -0,0 +1,51 @@
+package teamcity
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxIdLength = 225
+
+var (
+	validId      = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	invalidIdRun = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// IdExistsError is returned when creating an entity whose id is already taken
+type IdExistsError struct {
+	Id string
+}
+
+func (e IdExistsError) Error() string {
+	return fmt.Sprintf("teamcity: id %q is already in use", e.Id)
+}
+
+// generateVcsRootId derives an id for a VCS root from its project and name the same way TeamCity does,
+// e.g. MyProject_GitHubComExampleRepo
+func generateVcsRootId(projectId, name string) string {
+	var parts []string
+	for _, word := range strings.Fields(invalidIdRun.ReplaceAllString(name, " ")) {
+		parts = append(parts, strings.ToUpper(word[:1])+word[1:])
+	}
+	id := strings.Join(parts, "")
+	if len(projectId) > 0 {
+		id = projectId + "_" + id
+	}
+	if !validId.MatchString(id) {
+		id = "VcsRoot_" + id
+	}
+	if len(id) > maxIdLength {
+		id = id[:maxIdLength]
+	}
+	return id
+}
+
+// validateId checks that id can be used as an external id in TeamCity
+func validateId(id string) error {
+	if len(id) > maxIdLength || !validId.MatchString(id) {
+		return fmt.Errorf("teamcity: invalid id %q: must start with a latin letter and contain only latin letters, digits and underscores", id)
+	}
+	return nil
+}